@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+	_ "time/tzdata" // promotion schedules name time zones, embed them for minimal images
+
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the pgx database/sql driver
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/pkittipat/try-cart/internal/app/service"
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/pkittipat/try-cart/internal/domain/exchange"
+	"github.com/pkittipat/try-cart/internal/domain/shipping"
+	infraexchange "github.com/pkittipat/try-cart/internal/infrastructure/exchange"
+	"github.com/pkittipat/try-cart/internal/infrastructure/repository"
+	v1 "github.com/pkittipat/try-cart/internal/interface/http/v1"
+	"github.com/shopspring/decimal"
+)
+
+type config struct {
+	addr            string
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	idleTimeout     time.Duration
+	shutdownTimeout time.Duration
+	ratesFile       string
+	databaseURL     string
+	sqlitePath      string
+	limits          cart.Limits
+}
+
+// loadConfig reads settings from flags, falling back to environment variables and then defaults
+func loadConfig() config {
+	var cfg config
+	flag.StringVar(&cfg.addr, "addr", envString("CART_ADDR", ":8080"), "listen address (env CART_ADDR)")
+	flag.DurationVar(&cfg.readTimeout, "read-timeout", envDuration("CART_READ_TIMEOUT", 10*time.Second), "HTTP read timeout (env CART_READ_TIMEOUT)")
+	flag.DurationVar(&cfg.writeTimeout, "write-timeout", envDuration("CART_WRITE_TIMEOUT", 10*time.Second), "HTTP write timeout (env CART_WRITE_TIMEOUT)")
+	flag.DurationVar(&cfg.idleTimeout, "idle-timeout", envDuration("CART_IDLE_TIMEOUT", 60*time.Second), "HTTP idle timeout (env CART_IDLE_TIMEOUT)")
+	flag.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", envDuration("CART_SHUTDOWN_TIMEOUT", 15*time.Second), "graceful shutdown timeout (env CART_SHUTDOWN_TIMEOUT)")
+	flag.StringVar(&cfg.ratesFile, "rates-file", envString("CART_RATES_FILE", ""), "JSON exchange rate file for display-currency quotes (env CART_RATES_FILE)")
+	flag.StringVar(&cfg.databaseURL, "database-url", envString("CART_DATABASE_URL", ""), "PostgreSQL URL to store carts in, carts are kept in memory when empty; products, coupons and stock are always in memory (env CART_DATABASE_URL)")
+	flag.StringVar(&cfg.sqlitePath, "sqlite-path", envString("CART_SQLITE_PATH", ""), "SQLite file to store carts in on a single node (env CART_SQLITE_PATH)")
+	flag.Int64Var(&cfg.limits.MaxLineQuantity, "max-line-quantity", envInt("CART_MAX_LINE_QUANTITY", 0), "maximum units per cart line, 0 for no limit (env CART_MAX_LINE_QUANTITY)")
+	flag.IntVar(&cfg.limits.MaxLines, "max-lines", int(envInt("CART_MAX_LINES", 0)), "maximum distinct lines per cart, 0 for no limit (env CART_MAX_LINES)")
+	maxTotal := flag.String("max-cart-total", envString("CART_MAX_TOTAL", "0"), "cart total ceiling, 0 for no limit (env CART_MAX_TOTAL)")
+	flag.Parse()
+
+	if cfg.databaseURL != "" && cfg.sqlitePath != "" {
+		log.Fatal("set either a database URL or a SQLite path, not both")
+	}
+
+	var err error
+	if cfg.limits.MaxTotal, err = decimal.NewFromString(*maxTotal); err != nil {
+		log.Fatalf("invalid max cart total: %v", err)
+	}
+	if err := cart.ValidateLimits(cfg.limits); err != nil {
+		log.Fatalf("invalid cart limits: %v", err)
+	}
+	return cfg
+}
+
+func envString(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int64) int64 {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return fallback
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return n
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return d
+}
+
+// loadRates reads the rate file, without one carts can only be quoted in their own currency
+func loadRates(path string) (exchange.ExchangeRateProvider, error) {
+	if path == "" {
+		return infraexchange.NewStaticProvider("THB", time.Time{}, nil)
+	}
+	return infraexchange.NewFileProvider(path)
+}
+
+// openPostgres connects to the database and brings its schema up to date
+func openPostgres(ctx context.Context, databaseURL string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := repository.MigratePostgres(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// openSQLite opens the database file and brings its schema up to date
+func openSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := repository.OpenSQLite(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.MigrateSQLite(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// releaseExpiredReservations periodically returns the stock held by abandoned carts
+func releaseExpiredReservations(ctx context.Context, cartSrv *service.CartService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := cartSrv.ReleaseExpiredReservations(ctx); err != nil {
+				log.Printf("release expired reservations: %v", err)
+			}
+		}
+	}
+}
+
+func main() {
+	if err := run(loadConfig()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run serves the API until SIGINT or SIGTERM. It returns errors instead of exiting
+// so the database is closed on every path.
+func run(cfg config) error {
+	cartRepo := repository.NewCartRepository()
+	switch {
+	case cfg.databaseURL != "":
+		db, err := openPostgres(context.Background(), cfg.databaseURL)
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer db.Close()
+		cartRepo = repository.NewPostgresCartRepository(db)
+	case cfg.sqlitePath != "":
+		db, err := openSQLite(context.Background(), cfg.sqlitePath)
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer db.Close()
+		cartRepo = repository.NewSQLiteCartRepository(db)
+	}
+	if cfg.databaseURL != "" || cfg.sqlitePath != "" {
+		log.Println("only carts are stored in the database, products, coupons, stock and purchase history are kept in memory and lost on restart")
+	}
+	couponRepo := repository.NewCouponRepository()
+	productRepo := repository.NewProductRepository()
+	stockRepo := repository.NewStockRepository()
+	historyRepo := repository.NewPurchaseHistoryRepository()
+	cartSrv := service.NewCartService(cartRepo, couponRepo, productRepo, stockRepo, historyRepo, cart.DefaultTaxTable(), cfg.limits)
+	catalogSrv := service.NewCatalogService(productRepo, stockRepo)
+	shippingSrv := service.NewShippingService(cartRepo, shipping.DefaultCalculator())
+
+	rates, err := loadRates(cfg.ratesFile)
+	if err != nil {
+		return fmt.Errorf("load exchange rates: %w", err)
+	}
+	quoteSrv := service.NewQuoteService(cartRepo, rates)
+
+	e := echo.New()
+	e.HideBanner = true
+	e.Use(middleware.Logger())
+	e.Use(middleware.Recover())
+	e.Server.ReadTimeout = cfg.readTimeout
+	e.Server.WriteTimeout = cfg.writeTimeout
+	e.Server.IdleTimeout = cfg.idleTimeout
+
+	v1.RegisterCartHandler(e.Group("/v1/carts"), cartSrv)
+	v1.RegisterShippingHandler(e.Group("/v1/carts"), shippingSrv)
+	v1.RegisterQuoteHandler(e.Group("/v1/carts"), quoteSrv)
+	v1.RegisterCouponHandler(e.Group("/v1/coupons"), cartSrv)
+	v1.RegisterCatalogHandler(e.Group("/v1/products"), catalogSrv)
+	v1.RegisterInventoryHandler(e.Group("/v1/inventory"), catalogSrv)
+	v1.RegisterPurchaseHandler(e.Group("/v1/users"), cartSrv)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go releaseExpiredReservations(ctx, cartSrv, time.Minute)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := e.Start(cfg.addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("server error: %w", err)
+	case <-ctx.Done():
+	}
+	log.Println("shutting down, draining in-flight requests")
+
+	// Shutdown stops accepting new connections and waits for active requests to finish
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+	log.Println("server stopped")
+	return nil
+}
@@ -1,15 +1,420 @@
 package service
 
-import "github.com/pkittipat/try-cart/internal/domain/repository"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/pkittipat/try-cart/internal/domain/catalog"
+	"github.com/pkittipat/try-cart/internal/domain/inventory"
+	"github.com/pkittipat/try-cart/internal/domain/repository"
+	"github.com/shopspring/decimal"
+)
+
+var ErrPromotionNotFound = errors.New("promotion not found in cart")
+
+// AnyVersion changes a cart whatever version the caller last saw. A change that races
+// another is applied again to the newer cart, and fails with a
+// *repository.VersionConflictError only if that keeps happening.
+const AnyVersion int64 = 0
+
+// maxUpdateAttempts bounds how often a change at AnyVersion is applied again after
+// racing other changes to the cart
+const maxUpdateAttempts = 10
 
 type CartService struct {
-	cartRepo repository.Cart
+	cartRepo   repository.Cart
+	couponRepo repository.Coupon
+	products   catalog.Product
+	stock      inventory.Stock
+	history    repository.PurchaseHistory
+	taxes      cart.TaxTable
+	limits     cart.Limits
+	now        cart.Clock
 }
 
 func NewCartService(
 	cartRepo repository.Cart,
+	couponRepo repository.Coupon,
+	products catalog.Product,
+	stock inventory.Stock,
+	history repository.PurchaseHistory,
+	taxes cart.TaxTable,
+	limits cart.Limits,
 ) *CartService {
 	return &CartService{
-		cartRepo: cartRepo,
+		cartRepo:   cartRepo,
+		couponRepo: couponRepo,
+		products:   products,
+		stock:      stock,
+		history:    history,
+		taxes:      taxes,
+		limits:     limits,
+		now:        time.Now,
+	}
+}
+
+// CreateCart creates a cart for the user.
+// When the user already owns a cart its ID is returned with repository.ErrCartExists.
+func (s *CartService) CreateCart(ctx context.Context, userID string) (string, error) {
+	return s.cartRepo.Create(ctx, userID)
+}
+
+// GetCart returns the cart with its version, which the methods that change the cart take
+func (s *CartService) GetCart(ctx context.Context, cartID string) (*cart.Cart, int64, error) {
+	return s.cartRepo.GetByIDWithVersion(ctx, cartID)
+}
+
+// GetCartByUserID returns the user's cart with its ID and version
+func (s *CartService) GetCartByUserID(ctx context.Context, userID string) (string, *cart.Cart, int64, error) {
+	return s.cartRepo.GetByUserIDWithVersion(ctx, userID)
+}
+
+// AddItem adds quantity units of a catalog product to the cart, priced as the catalog has it,
+// and reserves them. Products with variants need the SKU of the variant, otherwise sku is empty.
+// It fails with an *inventory.OutOfStockError when too few units are available.
+func (s *CartService) AddItem(ctx context.Context, cartID string, version int64, productID, sku string, quantity int64) (*cart.Cart, int64, error) {
+	product, err := s.products.GetByID(ctx, productID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if sku != "" {
+		if product, err = product.Variant(sku); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return s.update(ctx, cartID, version, func(c *cart.Cart) error {
+		sku := product.LineSKU()
+		reserved := quantity
+		if item, ok := c.Items[sku]; ok {
+			reserved += item.Quantity
+		}
+
+		// Reserve before changing the cart so a refused reservation leaves it untouched
+		if err := s.reserve(ctx, cartID, sku, reserved); err != nil {
+			return err
+		}
+		if err := c.AddProduct(product, quantity); err != nil {
+			return errors.Join(err, s.syncReservation(ctx, cartID, c, sku))
+		}
+		return nil
+	})
+}
+
+// AddMeasuredItem adds an amount of a measured catalog product, e.g. 1.25 kg of coffee.
+// Stock is counted in whole units, so measured lines reserve nothing.
+func (s *CartService) AddMeasuredItem(ctx context.Context, cartID string, version int64, productID, sku string, measure decimal.Decimal) (*cart.Cart, int64, error) {
+	product, err := s.products.GetByID(ctx, productID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if sku != "" {
+		if product, err = product.Variant(sku); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return s.update(ctx, cartID, version, func(c *cart.Cart) error {
+		return c.AddMeasure(product, measure)
+	})
+}
+
+// UpdateItem sets the quantity of a line that is already in the cart and its reservation
+func (s *CartService) UpdateItem(ctx context.Context, cartID string, version int64, sku string, quantity int64) (*cart.Cart, int64, error) {
+	return s.update(ctx, cartID, version, func(c *cart.Cart) error {
+		if err := s.reserve(ctx, cartID, sku, quantity); err != nil {
+			return err
+		}
+		if err := c.SetQuantity(sku, quantity); err != nil {
+			return errors.Join(err, s.syncReservation(ctx, cartID, c, sku))
+		}
+		return nil
+	})
+}
+
+// UpdateMeasuredItem sets the amount of a measured line that is already in the cart
+func (s *CartService) UpdateMeasuredItem(ctx context.Context, cartID string, version int64, sku string, measure decimal.Decimal) (*cart.Cart, int64, error) {
+	return s.update(ctx, cartID, version, func(c *cart.Cart) error {
+		return c.SetMeasure(sku, measure)
+	})
+}
+
+// RemoveItem removes the line and releases its reservation
+func (s *CartService) RemoveItem(ctx context.Context, cartID string, version int64, sku string) (*cart.Cart, int64, error) {
+	return s.update(ctx, cartID, version, func(c *cart.Cart) error {
+		if err := c.RemoveProduct(sku); err != nil {
+			return err
+		}
+		return s.stock.Release(ctx, cartID, sku)
+	})
+}
+
+// ClearItems empties the cart and releases its reservations, keeping any total discount promotion
+func (s *CartService) ClearItems(ctx context.Context, cartID string, version int64) (*cart.Cart, int64, error) {
+	return s.update(ctx, cartID, version, func(c *cart.Cart) error {
+		c.Clear()
+		return s.stock.ReleaseCart(ctx, cartID)
+	})
+}
+
+// DeleteCart deletes the cart and releases its reservations. version is the version
+// the caller last saw, or AnyVersion.
+func (s *CartService) DeleteCart(ctx context.Context, cartID string, version int64) error {
+	var err error
+	if version == AnyVersion {
+		err = s.cartRepo.Delete(ctx, cartID)
+	} else {
+		err = s.cartRepo.DeleteIfVersion(ctx, cartID, version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.stock.ReleaseCart(ctx, cartID)
+}
+
+// ReleaseExpiredReservations returns the units of carts left untouched for longer
+// than the reservation TTL to the available stock
+func (s *CartService) ReleaseExpiredReservations(ctx context.Context) (int, error) {
+	return s.stock.ReleaseExpired(ctx, s.now())
+}
+
+// ApplyPromotion adds the promotion to the cart. Promotions that count units fail
+// with cart.ErrWholeUnitPromotion when the cart sells a product they name by measure.
+func (s *CartService) ApplyPromotion(ctx context.Context, cartID string, version int64, promotion cart.Promotion) (*cart.Cart, int64, error) {
+	if err := cart.ValidatePromotion(promotion); err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", cart.ErrInvalidPromotion, err)
+	}
+
+	return s.update(ctx, cartID, version, func(c *cart.Cart) error {
+		if err := c.CheckPromotion(promotion); err != nil {
+			return err
+		}
+		c.AddPromotion(promotion)
+		return nil
+	})
+}
+
+func (s *CartService) RemovePromotion(ctx context.Context, cartID string, version int64, promotionID string) (*cart.Cart, int64, error) {
+	return s.update(ctx, cartID, version, func(c *cart.Cart) error {
+		if !c.RemovePromotion(promotionID) {
+			return ErrPromotionNotFound
+		}
+		return nil
+	})
+}
+
+// CreateCoupon registers a coupon code that customers can apply to their carts
+func (s *CartService) CreateCoupon(ctx context.Context, coupon cart.Coupon) error {
+	if err := cart.ValidateCoupon(coupon); err != nil {
+		return fmt.Errorf("%w: %w", cart.ErrInvalidCoupon, err)
+	}
+
+	return s.couponRepo.Create(ctx, coupon)
+}
+
+// ApplyCoupon adds the promotion behind the code to the cart and counts a redemption
+// for the cart's owner. Rejections are returned as *cart.CouponRejection.
+func (s *CartService) ApplyCoupon(ctx context.Context, cartID string, version int64, code string) (*cart.Cart, int64, error) {
+	// Per-user limits count the owner the repository knows, never a user the caller names
+	userID, err := s.cartRepo.GetOwnerID(ctx, cartID)
+	if err != nil {
+		return nil, 0, err
+	}
+	coupon, err := s.couponRepo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return s.updateWithUndo(ctx, cartID, version, func(c *cart.Cart) error {
+		now := s.now()
+		// Check before redeeming so a rejected cart does not use up the coupon
+		if err := c.CheckCoupon(coupon, now); err != nil {
+			return err
+		}
+		if err := s.couponRepo.Redeem(ctx, coupon.Code, userID); err != nil {
+			return err
+		}
+		if err := c.ApplyCoupon(coupon, now); err != nil {
+			return errors.Join(err, s.couponRepo.CancelRedemption(ctx, coupon.Code, userID))
+		}
+		return nil
+	}, func() error {
+		return s.couponRepo.CancelRedemption(ctx, coupon.Code, userID)
+	})
+}
+
+// SetTaxRegion taxes the cart with the policy of the region, an empty region removes tax
+func (s *CartService) SetTaxRegion(ctx context.Context, cartID string, version int64, region string) (*cart.Cart, int64, error) {
+	var policy *cart.TaxPolicy
+	if region != "" {
+		p, err := s.taxes.Policy(region)
+		if err != nil {
+			return nil, 0, err
+		}
+		policy = &p
+	}
+
+	return s.update(ctx, cartID, version, func(c *cart.Cart) error {
+		c.Tax = policy
+		return nil
+	})
+}
+
+// GetBreakdown prices the cart line by line and returns the version it priced
+func (s *CartService) GetBreakdown(ctx context.Context, cartID string) (cart.PriceBreakdown, int64, error) {
+	c, version, err := s.cartRepo.GetByIDWithVersion(ctx, cartID)
+	if err != nil {
+		return cart.PriceBreakdown{}, 0, err
+	}
+
+	return c.Breakdown(), version, nil
+}
+
+// reserve holds quantity units of the SKU for the cart for the reservation TTL
+func (s *CartService) reserve(ctx context.Context, cartID, sku string, quantity int64) error {
+	return s.stock.Reserve(ctx, cartID, sku, quantity, s.reservationExpiry())
+}
+
+// reservationExpiry is when a reservation made or extended now expires
+func (s *CartService) reservationExpiry() time.Time {
+	return s.now().Add(inventory.DefaultReservationTTL)
+}
+
+// syncReservation makes the reservation of the SKU match the cart line again
+func (s *CartService) syncReservation(ctx context.Context, cartID string, c *cart.Cart, sku string) error {
+	if item, ok := c.Items[sku]; ok {
+		return s.reserve(ctx, cartID, sku, item.Quantity)
+	}
+	return s.stock.Release(ctx, cartID, sku)
+}
+
+// RecordPurchase adds units, product ID -> units, to what the user bought, so
+// MaxPerCustomer counts them on the user's next cart change
+func (s *CartService) RecordPurchase(ctx context.Context, userID string, units map[string]int64) error {
+	if len(units) == 0 {
+		return fmt.Errorf("%w: no units to record", cart.ErrInvalidQuantity)
+	}
+	for productID, quantity := range units {
+		if quantity <= 0 {
+			return fmt.Errorf("%w: %d units of product %s", cart.ErrInvalidQuantity, quantity, productID)
+		}
+		if _, err := s.products.GetByID(ctx, productID); err != nil {
+			return err
+		}
+	}
+
+	return s.history.Record(ctx, userID, units)
+}
+
+// GetPurchases returns what the user has bought, product ID -> units
+func (s *CartService) GetPurchases(ctx context.Context, userID string) (map[string]int64, error) {
+	return s.history.Purchased(ctx, userID)
+}
+
+// purchased returns what the cart's owner has bought before, product ID -> units
+func (s *CartService) purchased(ctx context.Context, cartID string) (map[string]int64, error) {
+	userID, err := s.cartRepo.GetOwnerID(ctx, cartID)
+	if err != nil {
+		return nil, err
+	}
+	return s.history.Purchased(ctx, userID)
+}
+
+// update loads the cart, applies fn and persists the result unless the cart changed
+// in the meantime. version is the version the caller last saw, or AnyVersion, in
+// which case a change that raced another is applied again to the newer cart.
+// The service's limits apply to every change, and MaxPerCustomer counts what the
+// cart's owner has bought before. A stored change holds all of the cart's reserved
+// units for another reservation TTL.
+func (s *CartService) update(ctx context.Context, cartID string, version int64, fn func(*cart.Cart) error) (*cart.Cart, int64, error) {
+	return s.updateWithUndo(ctx, cartID, version, fn, nil)
+}
+
+// updateWithUndo is update for a change with side effects beyond the cart's
+// reservations. undo reverts them when fn succeeded but the cart was not stored.
+func (s *CartService) updateWithUndo(ctx context.Context, cartID string, version int64, fn func(*cart.Cart) error, undo func() error) (*cart.Cart, int64, error) {
+	// unstored holds the carts of the attempts that lost a race; their reservations
+	// stay until an attempt is stored or they are restored on failure
+	var unstored []*cart.Cart
+	fail := func(err error) (*cart.Cart, int64, error) {
+		if len(unstored) > 0 {
+			err = errors.Join(err, s.restoreReservations(ctx, cartID, unstored...))
+		}
+		return nil, 0, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		c, current, err := s.cartRepo.GetByIDWithVersion(ctx, cartID)
+		if err != nil {
+			return fail(err)
+		}
+		// Refuse a stale cart before fn reserves stock or redeems a coupon for it
+		if version != AnyVersion && version != current {
+			return fail(&repository.VersionConflictError{CartID: cartID, Expected: version, Current: current})
+		}
+		c.Limits = s.limits
+		if c.Purchased, err = s.purchased(ctx, cartID); err != nil {
+			return fail(err)
+		}
+
+		if err := fn(c); err != nil {
+			if len(unstored) > 0 {
+				unstored = append(unstored, c)
+			}
+			return fail(err)
+		}
+
+		stored, err := s.cartRepo.UpdateIfVersion(ctx, cartID, c, current)
+		if err == nil {
+			// Updating the cart keeps every line's units held, not only the changed one's
+			if err := s.stock.Extend(ctx, cartID, s.reservationExpiry()); err != nil {
+				return nil, 0, err
+			}
+			return c, stored, nil
+		}
+		unstored = append(unstored, c)
+		// The undo runs once per unstored attempt, before any retry redoes fn
+		if undo != nil {
+			if undoErr := undo(); undoErr != nil {
+				return fail(errors.Join(err, undoErr))
+			}
+		}
+		// Another attempt reserves the lines again from the newer cart
+		if version == AnyVersion && attempt < maxUpdateAttempts && errors.Is(err, repository.ErrVersionConflict) {
+			continue
+		}
+		return fail(err)
+	}
+}
+
+// restoreReservations makes the reservations of the lines of the unstored carts
+// match the stored cart again
+func (s *CartService) restoreReservations(ctx context.Context, cartID string, unstored ...*cart.Cart) error {
+	stored, err := s.cartRepo.GetByID(ctx, cartID)
+	if errors.Is(err, repository.ErrCartNotFound) {
+		return s.stock.ReleaseCart(ctx, cartID)
+	}
+	if err != nil {
+		return err
+	}
+
+	skus := make(map[string]bool)
+	for _, c := range append(unstored, stored) {
+		for sku, item := range c.Items {
+			// Measured lines reserve nothing
+			if !item.Product.IsMeasured() {
+				skus[sku] = true
+			}
+		}
+	}
+
+	var errs []error
+	for sku := range skus {
+		errs = append(errs, s.syncReservation(ctx, cartID, stored, sku))
 	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/pkittipat/try-cart/internal/domain/catalog"
+	"github.com/pkittipat/try-cart/internal/domain/inventory"
+)
+
+type CatalogService struct {
+	products catalog.Product
+	stock    inventory.Stock
+}
+
+func NewCatalogService(products catalog.Product, stock inventory.Stock) *CatalogService {
+	return &CatalogService{
+		products: products,
+		stock:    stock,
+	}
+}
+
+// CreateProduct adds a product to the catalog and starts tracking the stock of
+// the variants that were given one
+func (s *CatalogService) CreateProduct(ctx context.Context, product cart.Product) error {
+	product, err := normalizeProduct(product)
+	if err != nil {
+		return err
+	}
+
+	if err := s.products.Create(ctx, withoutStock(product)); err != nil {
+		return err
+	}
+	for _, variant := range product.Variants {
+		if variant.Stock == nil {
+			continue
+		}
+		if err := s.stock.SetStock(ctx, variant.SKU, *variant.Stock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CatalogService) GetProduct(ctx context.Context, productID string) (cart.Product, error) {
+	return s.products.GetByID(ctx, productID)
+}
+
+func (s *CatalogService) ListProducts(ctx context.Context) ([]cart.Product, error) {
+	return s.products.List(ctx)
+}
+
+// UpdateProduct replaces a product. Carts that already hold it keep the price it
+// had when it was added, and the stock of its variants is left to SetStock.
+func (s *CatalogService) UpdateProduct(ctx context.Context, product cart.Product) error {
+	product, err := normalizeProduct(product)
+	if err != nil {
+		return err
+	}
+
+	return s.products.Update(ctx, withoutStock(product))
+}
+
+func (s *CatalogService) DeleteProduct(ctx context.Context, productID string) error {
+	return s.products.Delete(ctx, productID)
+}
+
+// SetStock sets the units on hand of a SKU, which starts tracking its stock
+func (s *CatalogService) SetStock(ctx context.Context, sku string, onHand int64) error {
+	if err := inventory.ValidateStock(onHand); err != nil {
+		return fmt.Errorf("%w: %w", cart.ErrInvalidProduct, err)
+	}
+
+	return s.stock.SetStock(ctx, sku, onHand)
+}
+
+// GetStock returns the stock of a SKU, ok is false when its stock is not tracked
+func (s *CatalogService) GetStock(ctx context.Context, sku string) (inventory.Level, bool, error) {
+	return s.stock.Level(ctx, sku)
+}
+
+// VariantStock returns the stock of the product's variants by SKU, leaving out
+// the ones whose stock is not tracked
+func (s *CatalogService) VariantStock(ctx context.Context, product cart.Product) (map[string]inventory.Level, error) {
+	levels := make(map[string]inventory.Level, len(product.Variants))
+	for _, variant := range product.Variants {
+		level, ok, err := s.stock.Level(ctx, variant.SKU)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			levels[variant.SKU] = level
+		}
+	}
+	return levels, nil
+}
+
+// withoutStock drops the starting stock of the variants, which inventory keeps
+// once the product is created
+func withoutStock(product cart.Product) cart.Product {
+	if len(product.Variants) == 0 {
+		return product
+	}
+
+	variants := make([]cart.Variant, len(product.Variants))
+	for i, variant := range product.Variants {
+		variant.Stock = nil
+		variants[i] = variant
+	}
+	product.Variants = variants
+	return product
+}
+
+func normalizeProduct(product cart.Product) (cart.Product, error) {
+	if err := cart.ValidateProduct(product); err != nil {
+		return cart.Product{}, fmt.Errorf("%w: %w", cart.ErrInvalidProduct, err)
+	}
+
+	product.Currency = strings.ToUpper(product.Currency)
+	return product, nil
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+
+	"github.com/pkittipat/try-cart/internal/domain/exchange"
+	"github.com/pkittipat/try-cart/internal/domain/repository"
+)
+
+type QuoteService struct {
+	cartRepo repository.Cart
+	rates    exchange.ExchangeRateProvider
+}
+
+func NewQuoteService(
+	cartRepo repository.Cart,
+	rates exchange.ExchangeRateProvider,
+) *QuoteService {
+	return &QuoteService{
+		cartRepo: cartRepo,
+		rates:    rates,
+	}
+}
+
+// Quote shows the cart in the display currency, checkout still charges
+// quote.Charge in the cart's own currency
+func (s *QuoteService) Quote(ctx context.Context, cartID, currency string) (exchange.Quote, error) {
+	c, err := s.cartRepo.GetByID(ctx, cartID)
+	if err != nil {
+		return exchange.Quote{}, err
+	}
+
+	return exchange.QuoteCart(ctx, s.rates, c, currency)
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+
+	"github.com/pkittipat/try-cart/internal/domain/repository"
+	"github.com/pkittipat/try-cart/internal/domain/shipping"
+)
+
+type ShippingService struct {
+	cartRepo   repository.Cart
+	calculator shipping.Calculator
+}
+
+func NewShippingService(
+	cartRepo repository.Cart,
+	calculator shipping.Calculator,
+) *ShippingService {
+	return &ShippingService{
+		cartRepo:   cartRepo,
+		calculator: calculator,
+	}
+}
+
+// Quote prices shipping the cart to the destination
+func (s *ShippingService) Quote(ctx context.Context, cartID string, dest shipping.Destination) (shipping.Quote, error) {
+	c, err := s.cartRepo.GetByID(ctx, cartID)
+	if err != nil {
+		return shipping.Quote{}, err
+	}
+
+	return s.calculator.Quote(c, dest)
+}
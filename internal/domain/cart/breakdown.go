@@ -0,0 +1,168 @@
+package cart
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type (
+	// PromotionSaving is the amount a single promotion took off
+	PromotionSaving struct {
+		PromotionID   string
+		PromotionType PromotionType
+		Amount        decimal.Decimal
+	}
+
+	// LineBreakdown describes how the total of a single cart line was reached
+	// Pricing fields cover the units left after bundles, see BundledQuantity.
+	LineBreakdown struct {
+		ProductID       string
+		SKU             string
+		Options         map[string]string // options of the line's variant
+		Description     string
+		Quantity        int64           // units in the cart
+		Measure         decimal.Decimal // amount of a measured line in Unit, UnitPrice is per Unit
+		Unit            UnitOfMeasure
+		BundledQuantity int64           // units priced by bundles instead of this line
+		UnitPrice       decimal.Decimal // price before any discount
+		DiscountedPrice decimal.Decimal // unit price at the reached tier after the product discount
+		TierSaving      decimal.Decimal // saved by quantity tiers over the whole line
+		ProductSaving   decimal.Decimal // saved by Product.Discount over the whole line
+		Promotions      []PromotionSaving
+		LineTotal       decimal.Decimal
+	}
+
+	// PriceBreakdown is the itemized result of pricing a cart
+	PriceBreakdown struct {
+		Lines          []LineBreakdown
+		Bundles        []BundleBreakdown
+		Subtotal       decimal.Decimal // sum of line and bundle totals
+		CartPromotions []PromotionSaving
+		Taxes          []TaxLine       // one line per tax category, empty when the cart is not taxed
+		Tax            decimal.Decimal // sum of the tax lines
+		Total          decimal.Decimal // amount payable, including tax
+		Currency       string          // ISO 4217 code of every amount
+	}
+)
+
+// Breakdown prices the cart at the instant given by its clock, see BreakdownAt
+func (c *Cart) Breakdown() PriceBreakdown {
+	return c.BreakdownAt(c.now())
+}
+
+// BreakdownAt prices bundles, then every line, then the cart-wide promotions and
+// finally tax, using the promotions active at the given instant.
+// Bundle, line, tax and grand totals are rounded to the cart's currency with its
+// rounding mode; the amounts inside a line are not. Lines are sorted by product ID
+// and then SKU.
+func (c *Cart) BreakdownAt(at time.Time) PriceBreakdown {
+	result := PriceBreakdown{
+		Lines:    make([]LineBreakdown, 0, len(c.Items)),
+		Subtotal: decimal.Zero,
+		Tax:      decimal.Zero,
+		Currency: c.Currency,
+	}
+
+	var remaining map[string]int64
+	result.Bundles, remaining = c.applyBundles(at)
+	for _, bundle := range result.Bundles {
+		result.Subtotal = result.Subtotal.Add(bundle.Total)
+	}
+
+	for _, item := range c.Items {
+		line := c.lineBreakdown(item, remaining, at)
+		result.Lines = append(result.Lines, line)
+		result.Subtotal = result.Subtotal.Add(line.LineTotal)
+	}
+	sort.Slice(result.Lines, func(i, j int) bool {
+		if result.Lines[i].ProductID != result.Lines[j].ProductID {
+			return result.Lines[i].ProductID < result.Lines[j].ProductID
+		}
+		return result.Lines[i].SKU < result.Lines[j].SKU
+	})
+
+	result.Total, result.CartPromotions = c.evaluatePromotions(c.CartPromotions, result.Subtotal, lineContext{at: at})
+	result.Total = c.round(result.Total)
+
+	if c.Tax != nil {
+		result.Taxes = c.Tax.calculateTax(c.taxableAmounts(result), c.round)
+		for _, tax := range result.Taxes {
+			result.Tax = result.Tax.Add(tax.Amount)
+		}
+		if c.Tax.Mode != TaxInclusive {
+			result.Total = result.Total.Add(result.Tax)
+		}
+	}
+
+	return result
+}
+
+// TotalSaving sums every tier, product discount and promotion saving in the breakdown
+func (b PriceBreakdown) TotalSaving() decimal.Decimal {
+	saving := decimal.Zero
+	for _, line := range b.Lines {
+		saving = saving.Add(line.TierSaving).Add(line.ProductSaving)
+		for _, promo := range line.Promotions {
+			saving = saving.Add(promo.Amount)
+		}
+	}
+	for _, bundle := range b.Bundles {
+		saving = saving.Add(bundle.Saving)
+	}
+	for _, promo := range b.CartPromotions {
+		saving = saving.Add(promo.Amount)
+	}
+
+	return saving
+}
+
+// lineBreakdown prices the units of the item that bundles did not consume.
+// remaining holds the quantities left by bundles, keyed by SKU.
+func (c *Cart) lineBreakdown(item *CartItem, remaining map[string]int64, at time.Time) LineBreakdown {
+	line, scope := c.unpromotedLine(item, remaining, at)
+	if scope.quantity == 0 && scope.measure.IsZero() {
+		return line
+	}
+
+	// Apply promotions to the discounted price
+	line.LineTotal, line.Promotions = c.evaluatePromotions(c.promotionsFor(item.Product), line.LineTotal, scope)
+	line.LineTotal = c.round(line.LineTotal)
+
+	return line
+}
+
+// unpromotedLine prices the line up to the product discount and returns the context
+// its promotions are evaluated in
+func (c *Cart) unpromotedLine(item *CartItem, remaining map[string]int64, at time.Time) (LineBreakdown, lineContext) {
+	product := item.Product
+	qty := remaining[product.LineSKU()]
+	amount := decimal.NewFromInt(qty)
+	// Quantity tiers first, then the product discount
+	tiered := product.TieredPrice(qty)
+	if product.IsMeasured() {
+		// Measured products have no tiers, Price is charged per unit of measure
+		amount = item.Measure
+		tiered = product.Price.Mul(amount)
+	}
+	gross := product.Price.Mul(amount)
+	discounted := product.applyDiscount(tiered)
+
+	line := LineBreakdown{
+		ProductID:       product.ID,
+		SKU:             product.LineSKU(),
+		Options:         product.Options,
+		Description:     product.Description,
+		Quantity:        item.Quantity,
+		Measure:         item.Measure,
+		Unit:            product.Unit,
+		BundledQuantity: item.Quantity - qty,
+		UnitPrice:       product.Price,
+		DiscountedPrice: product.applyDiscount(product.UnitPriceAt(qty)),
+		TierSaving:      gross.Sub(tiered),
+		ProductSaving:   tiered.Sub(discounted),
+		LineTotal:       discounted,
+	}
+	return line, lineContext{quantity: qty, measure: item.Measure, quantities: c.productQuantities(remaining), at: at}
+}
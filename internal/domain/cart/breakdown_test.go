@@ -0,0 +1,75 @@
+package cart
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCart_Breakdown(t *testing.T) {
+	cart := NewCart()
+	err := cart.AddProduct(Product{ID: "B", Price: decimal.NewFromFloat(50.00)}, 3)
+	require.NoError(t, err)
+	err = cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(100.00), Discount: 10}, 2)
+	require.NoError(t, err)
+	cart.AddPromotion(Promotion{ID: "P1", ProductID: "A", PromotionType: PercentageDiscount, Discount: 50})
+	cart.AddPromotion(Promotion{ID: "P2", ProductID: "B", PromotionType: Buy1Get1Free})
+	cart.AddPromotion(Promotion{ID: "P3", PromotionType: TotalDiscount, Discount: 10})
+
+	got := cart.Breakdown()
+
+	require.Len(t, got.Lines, 2)
+
+	lineA := got.Lines[0]
+	assert.Equal(t, "A", lineA.ProductID)
+	assert.True(t, decimal.NewFromFloat(100.00).Equal(lineA.UnitPrice))
+	assert.True(t, decimal.NewFromFloat(90.00).Equal(lineA.DiscountedPrice))
+	assert.True(t, decimal.NewFromFloat(20.00).Equal(lineA.ProductSaving)) // (100 - 90) * 2
+	require.Len(t, lineA.Promotions, 1)
+	assert.Equal(t, "P1", lineA.Promotions[0].PromotionID)
+	assert.True(t, decimal.NewFromFloat(90.00).Equal(lineA.Promotions[0].Amount)) // 180 * 0.5
+	assert.True(t, decimal.NewFromFloat(90.00).Equal(lineA.LineTotal))
+
+	lineB := got.Lines[1]
+	assert.Equal(t, "B", lineB.ProductID)
+	assert.True(t, decimal.Zero.Equal(lineB.ProductSaving))
+	require.Len(t, lineB.Promotions, 1)
+	assert.Equal(t, Buy1Get1Free, lineB.Promotions[0].PromotionType)
+	assert.True(t, decimal.NewFromFloat(50.00).Equal(lineB.Promotions[0].Amount)) // 1 of 3 free
+	assert.True(t, decimal.NewFromFloat(100.00).Equal(lineB.LineTotal))
+
+	assert.True(t, decimal.NewFromFloat(190.00).Equal(got.Subtotal))
+	require.Len(t, got.CartPromotions, 1)
+	assert.Equal(t, "P3", got.CartPromotions[0].PromotionID)
+	assert.True(t, decimal.NewFromFloat(19.00).Equal(got.CartPromotions[0].Amount))
+	assert.True(t, decimal.NewFromFloat(171.00).Equal(got.Total))
+	assert.True(t, got.Total.Equal(cart.CalculateTotal()))
+
+	// 350 gross - 171 total
+	assert.True(t, decimal.NewFromFloat(179.00).Equal(got.TotalSaving()))
+}
+
+func TestCart_Breakdown_UnmetMinSpend(t *testing.T) {
+	cart := NewCart()
+	require.NoError(t, cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(100.00)}, 5))
+	// Exclusive by default, but it saves nothing below its minimum spend
+	cart.AddPromotion(Promotion{ID: "spend1000", PromotionType: FixedCartDiscount, Amount: decimal.NewFromFloat(100.00), MinSpend: decimal.NewFromFloat(1000.00)})
+	cart.AddPromotion(Promotion{ID: "all5", PromotionType: TotalDiscount, Discount: 5})
+
+	got := cart.Breakdown()
+
+	require.Len(t, got.CartPromotions, 1)
+	assert.Equal(t, "all5", got.CartPromotions[0].PromotionID)
+	assert.True(t, decimal.NewFromFloat(475.00).Equal(got.Total), "got %s", got.Total)
+}
+
+func TestCart_Breakdown_Empty(t *testing.T) {
+	got := NewCart().Breakdown()
+
+	assert.Empty(t, got.Lines)
+	assert.Empty(t, got.CartPromotions)
+	assert.True(t, decimal.Zero.Equal(got.Subtotal))
+	assert.True(t, decimal.Zero.Equal(got.Total))
+}
@@ -0,0 +1,110 @@
+package cart
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type (
+	// BundleItem is one component of a bundle promotion
+	BundleItem struct {
+		ProductID string
+		Quantity  int64 // units needed per bundle
+	}
+
+	// BundleComponent records the units of one SKU consumed by a bundle
+	BundleComponent struct {
+		ProductID string
+		SKU       string
+		Quantity  int64
+	}
+
+	// BundleBreakdown describes the bundles formed by one bundle promotion
+	BundleBreakdown struct {
+		PromotionID string
+		Count       int64           // complete bundles formed
+		BundlePrice decimal.Decimal // price of one bundle
+		Components  []BundleComponent
+		Saving      decimal.Decimal // consumed units at their list price minus Total
+		Total       decimal.Decimal
+	}
+)
+
+// applyBundles forms as many complete bundles as the cart allows, taking the bundle
+// promotions active at the given instant in priority order, and returns the
+// quantities left for the lines keyed by SKU. Bundle items name products, units
+// are taken from the product's variants in SKU order. A bundle that would not cost
+// less than its units at their list price is not formed.
+func (c *Cart) applyBundles(at time.Time) ([]BundleBreakdown, map[string]int64) {
+	remaining := c.lineQuantities()
+
+	var bundles []BundleBreakdown
+	for _, promo := range sortPromotions(activePromotions(c.Bundles, at)) {
+		count := promo.bundleCount(c.productQuantities(remaining))
+		if count == 0 {
+			continue
+		}
+
+		bundle := BundleBreakdown{
+			PromotionID: promo.ID,
+			Count:       count,
+			BundlePrice: promo.Amount,
+			Total:       c.round(promo.Amount.Mul(decimal.NewFromInt(count))),
+		}
+		gross := decimal.Zero
+		consumedBySKU := make(map[string]int64)
+		for _, component := range promo.BundleItems {
+			needed := component.Quantity * count
+			for _, sku := range c.skusOf(component.ProductID) {
+				consumed := min(needed, remaining[sku])
+				if consumed == 0 {
+					continue
+				}
+				needed -= consumed
+				consumedBySKU[sku] = consumed
+				bundle.Components = append(bundle.Components, BundleComponent{
+					ProductID: component.ProductID,
+					SKU:       sku,
+					Quantity:  consumed,
+				})
+
+				unitPrice := c.Items[sku].Product.Price
+				gross = gross.Add(unitPrice.Mul(decimal.NewFromInt(consumed)))
+			}
+		}
+		bundle.Saving = gross.Sub(bundle.Total)
+		if !bundle.Saving.IsPositive() {
+			continue
+		}
+		for sku, consumed := range consumedBySKU {
+			remaining[sku] -= consumed
+		}
+		bundles = append(bundles, bundle)
+	}
+
+	return bundles, remaining
+}
+
+// bundleCount returns how many complete bundles the available quantities can form
+func (p *Promotion) bundleCount(available map[string]int64) int64 {
+	if len(p.BundleItems) == 0 {
+		return 0
+	}
+
+	count := int64(-1)
+	for _, component := range p.BundleItems {
+		if component.Quantity <= 0 {
+			return 0
+		}
+		n := available[component.ProductID] / component.Quantity
+		if count < 0 || n < count {
+			count = n
+		}
+	}
+	if p.MaxApplications > 0 && count > p.MaxApplications {
+		count = p.MaxApplications
+	}
+
+	return count
+}
@@ -0,0 +1,258 @@
+package cart
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCart_CalculateTotal_Bundles(t *testing.T) {
+	kit := Promotion{
+		ID:            "KIT",
+		PromotionType: BundlePrice,
+		Amount:        decimal.NewFromFloat(299.00),
+		BundleItems: []BundleItem{
+			{ProductID: "A", Quantity: 1},
+			{ProductID: "B", Quantity: 1},
+			{ProductID: "C", Quantity: 1},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		quantities map[string]int64
+		promotions []Promotion
+		want       decimal.Decimal
+	}{
+		{
+			name:       "one complete bundle",
+			quantities: map[string]int64{"A": 1, "B": 1, "C": 1},
+			promotions: []Promotion{kit},
+			want:       decimal.NewFromFloat(299.00),
+		},
+		{
+			name:       "leftover units at their normal price",
+			quantities: map[string]int64{"A": 3, "B": 2, "C": 2},
+			promotions: []Promotion{kit},
+			want:       decimal.NewFromFloat(688.00), // 2 * 299 + 90 (A after 10% off)
+		},
+		{
+			name:       "incomplete bundle",
+			quantities: map[string]int64{"A": 1, "B": 1},
+			promotions: []Promotion{kit},
+			want:       decimal.NewFromFloat(240.00), // 90 + 150
+		},
+		{
+			name:       "max applications",
+			quantities: map[string]int64{"A": 2, "B": 2, "C": 2},
+			promotions: []Promotion{func() Promotion { p := kit; p.MaxApplications = 1; return p }()},
+			want:       decimal.NewFromFloat(639.00), // 299 + 90 + 150 + 100
+		},
+		{
+			name:       "several units per bundle",
+			quantities: map[string]int64{"A": 5, "B": 1},
+			promotions: []Promotion{{
+				ID:            "PAIR",
+				PromotionType: BundlePrice,
+				Amount:        decimal.NewFromFloat(300.00),
+				BundleItems:   []BundleItem{{ProductID: "A", Quantity: 2}, {ProductID: "B", Quantity: 1}},
+			}},
+			want: decimal.NewFromFloat(570.00), // 300 + 3 * 90, B is fully consumed by the first bundle
+		},
+		{
+			name:       "bundles take units in priority order",
+			quantities: map[string]int64{"A": 1, "B": 1, "C": 1},
+			promotions: []Promotion{
+				kit,
+				{
+					ID:            "AB",
+					PromotionType: BundlePrice,
+					Amount:        decimal.NewFromFloat(200.00),
+					Priority:      -1,
+					BundleItems:   []BundleItem{{ProductID: "A", Quantity: 1}, {ProductID: "B", Quantity: 1}},
+				},
+			},
+			want: decimal.NewFromFloat(300.00), // AB + C, nothing left for KIT
+		},
+		{
+			name:       "bundle dearer than its units is not formed",
+			quantities: map[string]int64{"A": 1, "B": 1},
+			promotions: []Promotion{{
+				ID:            "AB",
+				PromotionType: BundlePrice,
+				Amount:        decimal.NewFromFloat(300.00),
+				BundleItems:   []BundleItem{{ProductID: "A", Quantity: 1}, {ProductID: "B", Quantity: 1}},
+			}},
+			want: decimal.NewFromFloat(240.00), // 90 + 150, the list price is 250
+		},
+		{
+			name:       "bundle dearer than its units leaves them to the next",
+			quantities: map[string]int64{"A": 1, "B": 1, "C": 1},
+			promotions: []Promotion{
+				kit,
+				{
+					ID:            "AB",
+					PromotionType: BundlePrice,
+					Amount:        decimal.NewFromFloat(250.00),
+					Priority:      -1,
+					BundleItems:   []BundleItem{{ProductID: "A", Quantity: 1}, {ProductID: "B", Quantity: 1}},
+				},
+			},
+			want: decimal.NewFromFloat(299.00),
+		},
+		{
+			name:       "product promotions apply to leftover units only",
+			quantities: map[string]int64{"A": 3, "B": 1, "C": 1},
+			promotions: []Promotion{kit, {ProductID: "A", PromotionType: Buy1Get1Free}},
+			want:       decimal.NewFromFloat(389.00), // 299 + 2 A with one free
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := newBundleCart(t, tt.quantities)
+			for _, promo := range tt.promotions {
+				require.NoError(t, ValidatePromotion(promo))
+				cart.AddPromotion(promo)
+			}
+
+			got := cart.CalculateTotal()
+			assert.True(t, tt.want.Equal(got), "Expected %s, got %s", tt.want.String(), got.String())
+		})
+	}
+}
+
+func TestCart_Breakdown_Bundles(t *testing.T) {
+	cart := newBundleCart(t, map[string]int64{"A": 3, "B": 2, "C": 2})
+	cart.AddPromotion(Promotion{
+		ID:            "KIT",
+		PromotionType: BundlePrice,
+		Amount:        decimal.NewFromFloat(299.00),
+		BundleItems: []BundleItem{
+			{ProductID: "A", Quantity: 1},
+			{ProductID: "B", Quantity: 1},
+			{ProductID: "C", Quantity: 1},
+		},
+	})
+
+	got := cart.Breakdown()
+
+	require.Len(t, got.Bundles, 1)
+	bundle := got.Bundles[0]
+	assert.Equal(t, "KIT", bundle.PromotionID)
+	assert.Equal(t, int64(2), bundle.Count)
+	assert.Equal(t, []BundleComponent{
+		{ProductID: "A", SKU: "A", Quantity: 2},
+		{ProductID: "B", SKU: "B", Quantity: 2},
+		{ProductID: "C", SKU: "C", Quantity: 2},
+	}, bundle.Components)
+	assert.True(t, decimal.NewFromFloat(598.00).Equal(bundle.Total))
+	assert.True(t, decimal.NewFromFloat(102.00).Equal(bundle.Saving)) // 2 * (100 + 150 + 100) - 598
+
+	require.Len(t, got.Lines, 3)
+	lineA := got.Lines[0]
+	assert.Equal(t, int64(3), lineA.Quantity)
+	assert.Equal(t, int64(2), lineA.BundledQuantity)
+	assert.True(t, decimal.NewFromFloat(10.00).Equal(lineA.ProductSaving))
+	assert.True(t, decimal.NewFromFloat(90.00).Equal(lineA.LineTotal))
+
+	lineB := got.Lines[1]
+	assert.Equal(t, int64(2), lineB.BundledQuantity)
+	assert.True(t, decimal.Zero.Equal(lineB.LineTotal))
+
+	assert.True(t, decimal.NewFromFloat(688.00).Equal(got.Subtotal))
+	assert.True(t, decimal.NewFromFloat(688.00).Equal(got.Total))
+	// 800 gross - 688 total
+	assert.True(t, decimal.NewFromFloat(112.00).Equal(got.TotalSaving()))
+}
+
+func TestCart_RemovePromotion_Bundle(t *testing.T) {
+	cart := newBundleCart(t, map[string]int64{"A": 1, "B": 1, "C": 1})
+	cart.AddPromotion(Promotion{
+		ID:            "KIT",
+		PromotionType: BundlePrice,
+		Amount:        decimal.NewFromFloat(299.00),
+		BundleItems:   []BundleItem{{ProductID: "A", Quantity: 1}, {ProductID: "B", Quantity: 1}},
+	})
+	require.Len(t, cart.Bundles, 1)
+	assert.Empty(t, cart.Promotion)
+
+	assert.True(t, cart.RemovePromotion("KIT"))
+	assert.Empty(t, cart.Bundles)
+	assert.True(t, decimal.NewFromFloat(340.00).Equal(cart.CalculateTotal()))
+}
+
+func TestValidatePromotion_Bundle(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    []BundleItem
+		amount   decimal.Decimal
+		errorMsg string
+	}{
+		{
+			name:   "valid bundle",
+			items:  []BundleItem{{ProductID: "A", Quantity: 1}, {ProductID: "B", Quantity: 2}},
+			amount: decimal.NewFromFloat(299.00),
+		},
+		{
+			name:     "no items",
+			amount:   decimal.NewFromFloat(299.00),
+			errorMsg: "bundle must have at least one item",
+		},
+		{
+			name:     "negative price",
+			items:    []BundleItem{{ProductID: "A", Quantity: 1}},
+			amount:   decimal.NewFromFloat(-1.00),
+			errorMsg: "bundle price cannot be negative",
+		},
+		{
+			name:     "empty product ID",
+			items:    []BundleItem{{ProductID: " ", Quantity: 1}},
+			amount:   decimal.NewFromFloat(299.00),
+			errorMsg: "bundle item product ID cannot be empty",
+		},
+		{
+			name:     "zero quantity",
+			items:    []BundleItem{{ProductID: "A", Quantity: 0}},
+			amount:   decimal.NewFromFloat(299.00),
+			errorMsg: "bundle item quantity must be positive",
+		},
+		{
+			name:     "duplicate product",
+			items:    []BundleItem{{ProductID: "A", Quantity: 1}, {ProductID: "A", Quantity: 1}},
+			amount:   decimal.NewFromFloat(299.00),
+			errorMsg: "bundle items must be distinct products",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePromotion(Promotion{PromotionType: BundlePrice, Amount: tt.amount, BundleItems: tt.items})
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.errorMsg, err.Error())
+		})
+	}
+}
+
+// newBundleCart fills a cart with A at 100 (10% off), B at 150 and C at 100
+func newBundleCart(t *testing.T, quantities map[string]int64) *Cart {
+	t.Helper()
+
+	products := map[string]Product{
+		"A": {ID: "A", Price: decimal.NewFromFloat(100.00), Discount: 10},
+		"B": {ID: "B", Price: decimal.NewFromFloat(150.00)},
+		"C": {ID: "C", Price: decimal.NewFromFloat(100.00)},
+	}
+
+	cart := NewCart()
+	for productID, qty := range quantities {
+		require.NoError(t, cart.AddProduct(products[productID], qty))
+	}
+	return cart
+}
@@ -3,93 +3,323 @@ package cart
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
 
+var (
+	ErrInvalidProduct   = errors.New("invalid product")
+	ErrInvalidQuantity  = errors.New("invalid quantity")
+	ErrInvalidPromotion = errors.New("invalid promotion")
+	ErrProductNotFound  = errors.New("product not found in cart")
+)
+
 type (
 	CartItem struct {
 		Product  Product
-		Quantity int64
+		Quantity int64           // units of a product sold by the unit
+		Measure  decimal.Decimal // amount of a measured product in its Unit, Quantity is then zero
 	}
 
 	Cart struct {
-		Items                  map[string]*CartItem
-		Promotion              map[string]*Promotion
-		TotalDiscountPromotion *Promotion
+		Items          map[string]*CartItem    // SKU -> line, a product without variants uses its ID
+		Promotion      map[string][]*Promotion // product ID -> promotions in the order they were added
+		CartPromotions []*Promotion            // cart-wide promotions such as TotalDiscount
+		Bundles        []*Promotion            // BundlePrice promotions spanning several products
+		Coupons        map[string]string       // applied coupon code -> promotion ID
+		Strategy       PromotionStrategy
+		Clock          Clock        // pricing instant for scheduled promotions, nil means time.Now
+		Tax            *TaxPolicy   // nil means the cart is not taxed
+		Currency       string       // ISO 4217 code, set by the first product that has one
+		Rounding       RoundingMode // how line, tax and grand totals are rounded to the currency, see SetRounding
+		Limits         Limits
+		Purchased      map[string]int64 // product ID -> units the customer bought before, for MaxPerCustomer
 	}
 )
 
 func NewCart() *Cart {
 	return &Cart{
 		Items:     make(map[string]*CartItem),
-		Promotion: make(map[string]*Promotion),
+		Promotion: make(map[string][]*Promotion),
+		Coupons:   make(map[string]string),
 	}
 }
 
+// Clone returns a copy of the cart whose lines, promotions and coupons can be
+// changed without affecting c. Products and promotions are shared, the cart
+// never changes them once they are added.
+func (c *Cart) Clone() *Cart {
+	clone := *c
+	clone.Items = make(map[string]*CartItem, len(c.Items))
+	for sku, item := range c.Items {
+		line := *item
+		clone.Items[sku] = &line
+	}
+	clone.Promotion = make(map[string][]*Promotion, len(c.Promotion))
+	for productID, promotions := range c.Promotion {
+		clone.Promotion[productID] = slices.Clone(promotions)
+	}
+	clone.CartPromotions = slices.Clone(c.CartPromotions)
+	clone.Bundles = slices.Clone(c.Bundles)
+	clone.Coupons = maps.Clone(c.Coupons)
+	clone.Purchased = maps.Clone(c.Purchased)
+	return &clone
+}
+
+// SetRounding selects how the cart's totals are rounded.
+// Unknown modes fail with ErrUnknownRoundingMode and leave the cart unchanged.
+func (c *Cart) SetRounding(mode RoundingMode) error {
+	if err := ValidateRoundingMode(mode); err != nil {
+		return err
+	}
+	c.Rounding = mode
+	return nil
+}
+
+// AddProduct adds units of the product to the line of its SKU.
+// A product with variants must first be resolved with Product.Variant, and
+// measured products are added with AddMeasure.
+// A change that would break the cart's Limits or the product's MaxPerCustomer
+// fails with a *LimitViolation and leaves the cart unchanged.
 func (c *Cart) AddProduct(product Product, quantity int64) error {
 	if err := ValidateProduct(product); err != nil {
-		return fmt.Errorf("invalid product: %w", err)
+		return fmt.Errorf("%w: %w", ErrInvalidProduct, err)
+	}
+	if len(product.Variants) > 0 {
+		return fmt.Errorf("%w: product %s has variants, add one of its SKUs", ErrInvalidProduct, product.ID)
+	}
+	if product.IsMeasured() {
+		return fmt.Errorf("%w: product %s is sold by %s, add a measure", ErrInvalidQuantity, product.ID, product.Unit)
 	}
 
 	if err := ValidateQuantity(quantity); err != nil {
-		return fmt.Errorf("invalid quantity: %w", err)
+		return fmt.Errorf("%w: %w", ErrInvalidQuantity, err)
 	}
 
-	if item, ok := c.Items[product.ID]; ok {
+	// Products without a currency are priced in the cart's currency
+	product.Currency = strings.ToUpper(product.Currency)
+	if product.Currency != "" && c.Currency != "" && product.Currency != c.Currency {
+		return fmt.Errorf("%w: cart is in %s, product %s is in %s", ErrCurrencyMismatch, c.Currency, product.ID, product.Currency)
+	}
+
+	sku := product.LineSKU()
+	item, ok := c.Items[sku]
+	var current int64
+	if ok {
+		current = item.Quantity
+	}
+	if err := c.checkLineLimits(product, current, current+quantity); err != nil {
+		return err
+	}
+
+	currency := c.Currency
+	if c.Currency == "" {
+		c.Currency = product.Currency
+	}
+	if ok {
 		item.Quantity += quantity
-		return nil
+	} else {
+		c.Items[sku] = &CartItem{Product: product, Quantity: quantity}
 	}
 
-	c.Items[product.ID] = &CartItem{Product: product, Quantity: quantity}
+	// The total ceiling needs the priced cart, undo the change when it is exceeded
+	if err := c.checkTotalLimit(); err != nil {
+		c.Currency = currency
+		if ok {
+			item.Quantity -= quantity
+		} else {
+			delete(c.Items, sku)
+		}
+		return err
+	}
 	return nil
 }
 
+// SetQuantity replaces the quantity of a line that is already in the cart, within
+// the same limits as AddProduct
+func (c *Cart) SetQuantity(sku string, quantity int64) error {
+	if err := ValidateQuantity(quantity); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidQuantity, err)
+	}
+
+	item, ok := c.Items[sku]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, sku)
+	}
+	if item.Product.IsMeasured() {
+		return fmt.Errorf("%w: product %s is sold by %s, set a measure", ErrInvalidQuantity, item.Product.ID, item.Product.Unit)
+	}
+	if err := c.checkLineLimits(item.Product, item.Quantity, quantity); err != nil {
+		return err
+	}
+
+	previous := item.Quantity
+	item.Quantity = quantity
+	if err := c.checkTotalLimit(); err != nil {
+		item.Quantity = previous
+		return err
+	}
+	return nil
+}
+
+// RemoveProduct removes the line of the SKU. The product's promotions, and the
+// coupons that added them, are removed with its last line.
+func (c *Cart) RemoveProduct(sku string) error {
+	item, ok := c.Items[sku]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, sku)
+	}
+
+	delete(c.Items, sku)
+	if len(c.skusOf(item.Product.ID)) == 0 {
+		delete(c.Promotion, item.Product.ID)
+		c.dropOrphanedCoupons()
+	}
+	return nil
+}
+
+// Clear removes every line with its product and bundle promotions and the coupons
+// that added them, keeping the cart-wide promotions
+func (c *Cart) Clear() {
+	c.Items = make(map[string]*CartItem)
+	c.Promotion = make(map[string][]*Promotion)
+	c.Bundles = nil
+	c.dropOrphanedCoupons()
+}
+
+// dropOrphanedCoupons removes the coupons whose promotion is no longer in the cart
+func (c *Cart) dropOrphanedCoupons() {
+	for code, promotionID := range c.Coupons {
+		if !c.hasPromotion(promotionID) {
+			delete(c.Coupons, code)
+		}
+	}
+}
+
+// hasPromotion reports whether a promotion with the ID is in the cart
+func (c *Cart) hasPromotion(promotionID string) bool {
+	for _, promotions := range c.Promotion {
+		if slices.ContainsFunc(promotions, func(p *Promotion) bool { return p.ID == promotionID }) {
+			return true
+		}
+	}
+	for _, promotions := range [][]*Promotion{c.CartPromotions, c.Bundles} {
+		if slices.ContainsFunc(promotions, func(p *Promotion) bool { return p.ID == promotionID }) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddPromotion adds a product or cart-wide promotion.
+// A promotion whose ID is already in the cart replaces the existing one.
 func (c *Cart) AddPromotion(promotion Promotion) {
-	if promotion.PromotionType == TotalDiscount {
-		c.TotalDiscountPromotion = &promotion
+	if promotion.ID != "" {
+		c.RemovePromotion(promotion.ID)
+	}
+
+	if promotion.IsCartWide() {
+		c.CartPromotions = append(c.CartPromotions, &promotion)
 		return
 	}
-	if _, ok := c.Promotion[promotion.ProductID]; ok {
+	if promotion.PromotionType == BundlePrice {
+		c.Bundles = append(c.Bundles, &promotion)
 		return
 	}
 
-	c.Promotion[promotion.ProductID] = &promotion
+	c.Promotion[promotion.ProductID] = append(c.Promotion[promotion.ProductID], &promotion)
 }
 
-func (c *Cart) CalculateTotal() decimal.Decimal {
-	total := decimal.Zero
-	for _, item := range c.Items {
-		// Apply product discount first
-		discountedPrice := item.Product.GetDiscountedPrice()
-		qty := item.Quantity
-		promo, hasPromo := c.Promotion[item.Product.ID]
-
-		if !hasPromo {
-			qtyDecimal := decimal.NewFromInt(qty)
-			total = total.Add(discountedPrice.Mul(qtyDecimal))
+// RemovePromotion removes the promotion with the given ID, reporting whether it was found.
+// A coupon that added the promotion is removed with it.
+func (c *Cart) RemovePromotion(promotionID string) bool {
+	for code, id := range c.Coupons {
+		if id == promotionID {
+			delete(c.Coupons, code)
+		}
+	}
+
+	if promotions, ok := removePromotionByID(c.CartPromotions, promotionID); ok {
+		c.CartPromotions = promotions
+		return true
+	}
+	if promotions, ok := removePromotionByID(c.Bundles, promotionID); ok {
+		c.Bundles = promotions
+		return true
+	}
+
+	for productID, promotions := range c.Promotion {
+		promotions, ok := removePromotionByID(promotions, promotionID)
+		if !ok {
 			continue
 		}
 
-		// Apply promotions to the discounted price
-		total = total.Add(promo.CalculatePrice(discountedPrice, qty))
+		if len(promotions) == 0 {
+			delete(c.Promotion, productID)
+		} else {
+			c.Promotion[productID] = promotions
+		}
+		return true
+	}
+
+	return false
+}
+
+func removePromotionByID(promotions []*Promotion, promotionID string) ([]*Promotion, bool) {
+	for i, promo := range promotions {
+		if promo.ID == promotionID {
+			return append(promotions[:i:i], promotions[i+1:]...), true
+		}
+	}
+
+	return promotions, false
+}
+
+// CalculateTotal returns the grand total, see Breakdown for the itemized result
+func (c *Cart) CalculateTotal() decimal.Decimal {
+	return c.Breakdown().Total
+}
+
+// TotalMoney returns the grand total in the cart's currency.
+// It fails when a line is priced in another currency.
+func (c *Cart) TotalMoney() (Money, error) {
+	if err := c.CheckCurrency(); err != nil {
+		return Money{}, err
 	}
 
-	if c.TotalDiscountPromotion != nil {
-		discount := decimal.NewFromInt(c.TotalDiscountPromotion.Discount)
-		hundred := decimal.NewFromInt(100)
-		total = total.Mul(hundred.Sub(discount)).Div(hundred)
+	return NewMoney(c.CalculateTotal(), c.Currency), nil
+}
+
+// CheckCurrency fails with ErrCurrencyMismatch when a line is priced in a currency
+// other than the cart's
+func (c *Cart) CheckCurrency() error {
+	for _, item := range c.Items {
+		if item.Product.Currency != "" && item.Product.Currency != c.Currency {
+			return fmt.Errorf("%w: cart is in %s, product %s is in %s", ErrCurrencyMismatch, c.Currency, item.Product.ID, item.Product.Currency)
+		}
 	}
+	return nil
+}
 
-	return total
+// CalculateTotalAt returns the grand total as of the given instant
+func (c *Cart) CalculateTotalAt(at time.Time) decimal.Decimal {
+	return c.BreakdownAt(at).Total
 }
 
+// DisplayPrice renders a price with 2 decimal places, see DisplayAmount for other currencies
 func DisplayPrice(price decimal.Decimal) string {
-	// Convert decimal price to string with 2 decimal places
 	return price.StringFixed(2)
 }
 
+// round rounds an amount to the cart's currency with its rounding mode
+func (c *Cart) round(amount decimal.Decimal) decimal.Decimal {
+	return c.Rounding.Round(amount, decimalsOf(c.Currency))
+}
+
 // ValidateProduct validates product data
 func ValidateProduct(product Product) error {
 	if strings.TrimSpace(product.ID) == "" {
@@ -104,6 +334,44 @@ func ValidateProduct(product Product) error {
 		return errors.New("product discount must be between 0 and 100")
 	}
 
+	if err := ValidateCurrency(product.Currency); err != nil {
+		return err
+	}
+
+	if product.MaxPerCustomer < 0 {
+		return errors.New("product purchase limit cannot be negative")
+	}
+
+	if product.Weight.IsNegative() || product.Length.IsNegative() || product.Width.IsNegative() || product.Height.IsNegative() {
+		return errors.New("product weight and dimensions cannot be negative")
+	}
+
+	if err := validateUnit(product); err != nil {
+		return err
+	}
+
+	if err := validateVariants(product); err != nil {
+		return err
+	}
+
+	switch product.TierMode {
+	case "", TierAllUnits, TierGraduated:
+	default:
+		return errors.New("unknown product tier mode")
+	}
+
+	for i, tier := range product.PriceTiers {
+		if tier.MinQuantity < 1 {
+			return errors.New("price tier minimum quantity must be positive")
+		}
+		if i > 0 && tier.MinQuantity <= product.PriceTiers[i-1].MinQuantity {
+			return errors.New("price tiers must be in ascending minimum quantity order")
+		}
+		if tier.UnitPrice.LessThan(decimal.Zero) {
+			return errors.New("price tier unit price cannot be negative")
+		}
+	}
+
 	return nil
 }
 
@@ -2,9 +2,11 @@ package cart
 
 import (
 	"testing"
+	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCart_AddProduct(t *testing.T) {
@@ -75,7 +77,7 @@ func TestCart_AddPromotion(t *testing.T) {
 		name      string
 		promotion Promotion
 		setup     func(*Cart)
-		want      map[string]*Promotion
+		want      map[string][]*Promotion
 	}{
 		{
 			name: "add new promotion",
@@ -85,16 +87,18 @@ func TestCart_AddPromotion(t *testing.T) {
 				Discount:      10,
 			},
 			setup: func(c *Cart) {},
-			want: map[string]*Promotion{
+			want: map[string][]*Promotion{
 				"1": {
-					ProductID:     "1",
-					PromotionType: PercentageDiscount,
-					Discount:      10,
+					{
+						ProductID:     "1",
+						PromotionType: PercentageDiscount,
+						Discount:      10,
+					},
 				},
 			},
 		},
 		{
-			name: "add existing promotion",
+			name: "add second promotion for same product",
 			promotion: Promotion{
 				ProductID:     "1",
 				PromotionType: PercentageDiscount,
@@ -107,11 +111,45 @@ func TestCart_AddPromotion(t *testing.T) {
 					Discount:      10,
 				})
 			},
-			want: map[string]*Promotion{
+			want: map[string][]*Promotion{
 				"1": {
+					{
+						ProductID:     "1",
+						PromotionType: PercentageDiscount,
+						Discount:      10,
+					},
+					{
+						ProductID:     "1",
+						PromotionType: PercentageDiscount,
+						Discount:      20,
+					},
+				},
+			},
+		},
+		{
+			name: "replace promotion with same ID",
+			promotion: Promotion{
+				ID:            "P1",
+				ProductID:     "1",
+				PromotionType: PercentageDiscount,
+				Discount:      20,
+			},
+			setup: func(c *Cart) {
+				c.AddPromotion(Promotion{
+					ID:            "P1",
 					ProductID:     "1",
 					PromotionType: PercentageDiscount,
 					Discount:      10,
+				})
+			},
+			want: map[string][]*Promotion{
+				"1": {
+					{
+						ID:            "P1",
+						ProductID:     "1",
+						PromotionType: PercentageDiscount,
+						Discount:      20,
+					},
 				},
 			},
 		},
@@ -127,6 +165,93 @@ func TestCart_AddPromotion(t *testing.T) {
 	}
 }
 
+func TestCart_AddPromotion_CartWide(t *testing.T) {
+	cart := NewCart()
+	cart.AddPromotion(Promotion{ID: "T1", PromotionType: TotalDiscount, Discount: 10})
+	cart.AddPromotion(Promotion{ID: "T2", PromotionType: TotalDiscount, Discount: 5})
+
+	assert.Empty(t, cart.Promotion)
+	require.Len(t, cart.CartPromotions, 2)
+	assert.Equal(t, "T1", cart.CartPromotions[0].ID)
+	assert.Equal(t, "T2", cart.CartPromotions[1].ID)
+
+	assert.True(t, cart.RemovePromotion("T1"))
+	assert.False(t, cart.RemovePromotion("T1"))
+	require.Len(t, cart.CartPromotions, 1)
+	assert.Equal(t, "T2", cart.CartPromotions[0].ID)
+}
+
+func TestCart_CalculateTotal_Stacking(t *testing.T) {
+	tests := []struct {
+		name       string
+		promotions []Promotion
+		want       decimal.Decimal
+	}{
+		{
+			name: "exclusive promotions apply first only",
+			promotions: []Promotion{
+				{ID: "P1", ProductID: "A", PromotionType: PercentageDiscount, Discount: 10},
+				{ID: "P2", ProductID: "A", PromotionType: PercentageDiscount, Discount: 50},
+			},
+			want: decimal.NewFromFloat(360.00), // 400 * 0.9
+		},
+		{
+			name: "priority decides which exclusive promotion applies",
+			promotions: []Promotion{
+				{ID: "P1", ProductID: "A", PromotionType: PercentageDiscount, Discount: 10, Priority: 2},
+				{ID: "P2", ProductID: "A", PromotionType: PercentageDiscount, Discount: 50, Priority: 1},
+			},
+			want: decimal.NewFromFloat(200.00), // 400 * 0.5
+		},
+		{
+			name: "stackable promotions compound in priority order",
+			promotions: []Promotion{
+				{ID: "P1", ProductID: "A", PromotionType: PercentageDiscount, Discount: 10, Stacking: Stackable},
+				{ID: "P2", ProductID: "A", PromotionType: Buy1Get1Free, Stacking: Stackable},
+			},
+			want: decimal.NewFromFloat(180.00), // 400 * 0.9 = 360, 2 of 4 paid = 180
+		},
+		{
+			name: "exclusive after stackable is skipped",
+			promotions: []Promotion{
+				{ID: "P1", ProductID: "A", PromotionType: PercentageDiscount, Discount: 10, Stacking: Stackable},
+				{ID: "P2", ProductID: "A", PromotionType: Buy1Get1Free, Priority: 1},
+			},
+			want: decimal.NewFromFloat(360.00),
+		},
+		{
+			name: "stackable cart-wide promotions",
+			promotions: []Promotion{
+				{ID: "T1", PromotionType: TotalDiscount, Discount: 10, Stacking: Stackable},
+				{ID: "T2", PromotionType: TotalDiscount, Discount: 50, Stacking: Stackable},
+			},
+			want: decimal.NewFromFloat(180.00), // 400 * 0.9 * 0.5
+		},
+		{
+			name: "exclusive cart-wide promotion",
+			promotions: []Promotion{
+				{ID: "T1", PromotionType: TotalDiscount, Discount: 10, Stacking: Stackable, Priority: 1},
+				{ID: "T2", PromotionType: TotalDiscount, Discount: 50},
+			},
+			want: decimal.NewFromFloat(200.00), // T2 comes first and applies alone
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart()
+			err := cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(100.00)}, 4)
+			require.NoError(t, err)
+			for _, promo := range tt.promotions {
+				cart.AddPromotion(promo)
+			}
+
+			got := cart.CalculateTotal()
+			assert.True(t, tt.want.Equal(got), "Expected %s, got %s", tt.want.String(), got.String())
+		})
+	}
+}
+
 func TestCart_CalculateTotal(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -647,6 +772,16 @@ func TestValidateProduct(t *testing.T) {
 			expectError: true,
 			errorMsg:    "product discount must be between 0 and 100",
 		},
+		{
+			name: "negative weight",
+			product: Product{
+				ID:     "valid-id",
+				Price:  decimal.NewFromFloat(100.00),
+				Weight: decimal.NewFromFloat(-1.00),
+			},
+			expectError: true,
+			errorMsg:    "product weight and dimensions cannot be negative",
+		},
 	}
 
 	for _, tt := range tests {
@@ -707,3 +842,277 @@ func TestValidateQuantity(t *testing.T) {
 		})
 	}
 }
+
+func TestCart_SetQuantity(t *testing.T) {
+	tests := []struct {
+		name      string
+		productID string
+		quantity  int64
+		wantErr   error
+		wantQty   int64
+	}{
+		{
+			name:      "decrease quantity",
+			productID: "A",
+			quantity:  1,
+			wantQty:   1,
+		},
+		{
+			name:      "increase quantity",
+			productID: "A",
+			quantity:  10,
+			wantQty:   10,
+		},
+		{
+			name:      "zero quantity",
+			productID: "A",
+			quantity:  0,
+			wantErr:   ErrInvalidQuantity,
+			wantQty:   3,
+		},
+		{
+			name:      "unknown product",
+			productID: "Z",
+			quantity:  1,
+			wantErr:   ErrProductNotFound,
+			wantQty:   3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart()
+			err := cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(10.00)}, 3)
+			assert.NoError(t, err)
+
+			err = cart.SetQuantity(tt.productID, tt.quantity)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantQty, cart.Items["A"].Quantity)
+		})
+	}
+}
+
+func TestCart_RemoveProduct(t *testing.T) {
+	tests := []struct {
+		name      string
+		productID string
+		wantErr   error
+		wantItems []string
+		wantPromo []string
+	}{
+		{
+			name:      "remove product with promotion",
+			productID: "A",
+			wantItems: []string{"B"},
+			wantPromo: []string{"B"},
+		},
+		{
+			name:      "unknown product",
+			productID: "Z",
+			wantErr:   ErrProductNotFound,
+			wantItems: []string{"A", "B"},
+			wantPromo: []string{"A", "B"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart()
+			for _, id := range []string{"A", "B"} {
+				err := cart.AddProduct(Product{ID: id, Price: decimal.NewFromFloat(10.00)}, 1)
+				assert.NoError(t, err)
+				cart.AddPromotion(Promotion{ProductID: id, PromotionType: PercentageDiscount, Discount: 10})
+			}
+
+			err := cart.RemoveProduct(tt.productID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			for _, id := range tt.wantItems {
+				assert.Contains(t, cart.Items, id)
+			}
+			assert.Len(t, cart.Items, len(tt.wantItems))
+			for _, id := range tt.wantPromo {
+				assert.Contains(t, cart.Promotion, id)
+			}
+			assert.Len(t, cart.Promotion, len(tt.wantPromo))
+		})
+	}
+}
+
+func TestCart_Clear(t *testing.T) {
+	cart := NewCart()
+	err := cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(10.00)}, 2)
+	assert.NoError(t, err)
+	cart.AddPromotion(Promotion{ProductID: "A", PromotionType: Buy1Get1Free})
+	cart.AddPromotion(Promotion{PromotionType: TotalDiscount, Discount: 10})
+	cart.AddPromotion(Promotion{ID: "AB", PromotionType: BundlePrice, BundleItems: []BundleItem{{ProductID: "A", Quantity: 1}, {ProductID: "B", Quantity: 1}}, Amount: decimal.NewFromFloat(15.00)})
+	require.NoError(t, cart.ApplyCoupon(Coupon{Code: "A5", Promotion: Promotion{ProductID: "A", PromotionType: PercentageDiscount, Discount: 5}}, time.Now()))
+	require.NoError(t, cart.ApplyCoupon(Coupon{Code: "ALL5", Promotion: Promotion{PromotionType: TotalDiscount, Discount: 5}}, time.Now()))
+
+	cart.Clear()
+
+	assert.Empty(t, cart.Items)
+	assert.Empty(t, cart.Promotion)
+	assert.Empty(t, cart.Bundles)
+	assert.Len(t, cart.CartPromotions, 2)
+	assert.Equal(t, map[string]string{"ALL5": "coupon:ALL5"}, cart.Coupons, "only the coupon of a kept promotion stays")
+	assert.True(t, decimal.Zero.Equal(cart.CalculateTotal()))
+}
+
+func TestProduct_TieredPrice(t *testing.T) {
+	tiers := []PriceTier{
+		{MinQuantity: 10, UnitPrice: decimal.NewFromFloat(9.00)},
+		{MinQuantity: 50, UnitPrice: decimal.NewFromFloat(8.00)},
+	}
+
+	tests := []struct {
+		name     string
+		mode     TierMode
+		discount int64
+		qty      int64
+		want     decimal.Decimal
+	}{
+		{
+			name: "below first tier",
+			qty:  9,
+			want: decimal.NewFromFloat(90.00),
+		},
+		{
+			name: "all units at reached tier",
+			mode: TierAllUnits,
+			qty:  10,
+			want: decimal.NewFromFloat(90.00),
+		},
+		{
+			name: "all units at top tier",
+			qty:  50,
+			want: decimal.NewFromFloat(400.00),
+		},
+		{
+			name: "graduated within second tier",
+			mode: TierGraduated,
+			qty:  12,
+			want: decimal.NewFromFloat(117.00), // 9 * 10 + 3 * 9
+		},
+		{
+			name: "graduated across all tiers",
+			mode: TierGraduated,
+			qty:  52,
+			want: decimal.NewFromFloat(474.00), // 9 * 10 + 40 * 9 + 3 * 8
+		},
+		{
+			name:     "product discount applies to tiered price",
+			qty:      10,
+			discount: 10,
+			want:     decimal.NewFromFloat(81.00),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := Product{
+				ID:         "A",
+				Price:      decimal.NewFromFloat(10.00),
+				Discount:   tt.discount,
+				PriceTiers: tiers,
+				TierMode:   tt.mode,
+			}
+			got := product.GetDiscountedLinePrice(tt.qty)
+			assert.True(t, tt.want.Equal(got), "Expected %s, got %s", tt.want.String(), got.String())
+
+			cart := NewCart()
+			err := cart.AddProduct(product, tt.qty)
+			require.NoError(t, err)
+			total := cart.CalculateTotal()
+			assert.True(t, tt.want.Equal(total), "Expected %s, got %s", tt.want.String(), total.String())
+		})
+	}
+}
+
+func TestValidateProduct_PriceTiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		product  Product
+		errorMsg string
+	}{
+		{
+			name: "valid tiers",
+			product: Product{ID: "A", Price: decimal.NewFromFloat(10.00), PriceTiers: []PriceTier{
+				{MinQuantity: 10, UnitPrice: decimal.NewFromFloat(9.00)},
+				{MinQuantity: 50, UnitPrice: decimal.NewFromFloat(8.00)},
+			}},
+		},
+		{
+			name: "tiers out of order",
+			product: Product{ID: "A", Price: decimal.NewFromFloat(10.00), PriceTiers: []PriceTier{
+				{MinQuantity: 50, UnitPrice: decimal.NewFromFloat(8.00)},
+				{MinQuantity: 10, UnitPrice: decimal.NewFromFloat(9.00)},
+			}},
+			errorMsg: "price tiers must be in ascending minimum quantity order",
+		},
+		{
+			name: "zero minimum quantity",
+			product: Product{ID: "A", Price: decimal.NewFromFloat(10.00), PriceTiers: []PriceTier{
+				{MinQuantity: 0, UnitPrice: decimal.NewFromFloat(8.00)},
+			}},
+			errorMsg: "price tier minimum quantity must be positive",
+		},
+		{
+			name: "negative tier price",
+			product: Product{ID: "A", Price: decimal.NewFromFloat(10.00), PriceTiers: []PriceTier{
+				{MinQuantity: 10, UnitPrice: decimal.NewFromFloat(-1.00)},
+			}},
+			errorMsg: "price tier unit price cannot be negative",
+		},
+		{
+			name:     "unknown tier mode",
+			product:  Product{ID: "A", Price: decimal.NewFromFloat(10.00), TierMode: "stepped"},
+			errorMsg: "unknown product tier mode",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProduct(tt.product)
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.errorMsg, err.Error())
+		})
+	}
+}
+
+func TestCart_Clone(t *testing.T) {
+	original := NewCart()
+	require.NoError(t, original.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(100.00)}, 2))
+	original.AddPromotion(Promotion{ID: "A10", ProductID: "A", PromotionType: PercentageDiscount, Discount: 10})
+	original.AddPromotion(Promotion{ID: "ALL5", PromotionType: TotalDiscount, Discount: 5})
+	original.Coupons["SAVE"] = "ALL5"
+	original.Purchased = map[string]int64{"A": 1}
+
+	clone := original.Clone()
+	require.NoError(t, clone.SetQuantity("A", 5))
+	require.NoError(t, clone.AddProduct(Product{ID: "B", Price: decimal.NewFromFloat(50.00)}, 1))
+	clone.RemovePromotion("A10")
+	clone.RemovePromotion("ALL5")
+	clone.Coupons["OTHER"] = "X"
+	clone.Purchased["A"] = 3
+
+	assert.Equal(t, int64(2), original.Items["A"].Quantity)
+	assert.NotContains(t, original.Items, "B")
+	assert.Len(t, original.Promotion["A"], 1)
+	assert.Len(t, original.CartPromotions, 1)
+	assert.Equal(t, map[string]string{"SAVE": "ALL5"}, original.Coupons)
+	assert.Equal(t, int64(1), original.Purchased["A"])
+	assert.True(t, decimal.NewFromFloat(171.00).Equal(original.CalculateTotal()), "got %s", original.CalculateTotal())
+}
@@ -0,0 +1,187 @@
+package cart
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidCoupon  = errors.New("invalid coupon")
+	ErrCouponRejected = errors.New("coupon rejected")
+)
+
+// RejectionReason tells the customer why a coupon code was not accepted
+type RejectionReason string
+
+const (
+	RejectNotStarted     RejectionReason = "notStarted"     // before ValidFrom
+	RejectExpired        RejectionReason = "expired"        // after ValidUntil
+	RejectExhausted      RejectionReason = "exhausted"      // a usage limit was reached
+	RejectNotEligible    RejectionReason = "notEligible"    // the cart has nothing the promotion applies to
+	RejectMinimumNotMet  RejectionReason = "minimumNotMet"  // the cart total is below MinSpend
+	RejectAlreadyApplied RejectionReason = "alreadyApplied" // the code is already on the cart
+)
+
+type (
+	// Coupon maps a customer-entered code to a promotion and its redemption rules
+	Coupon struct {
+		Code      string
+		Promotion Promotion
+
+		// Zero values mean no limit
+		ValidFrom             time.Time
+		ValidUntil            time.Time       // exclusive
+		MinSpend              decimal.Decimal // cart total required before the coupon is applied
+		MaxRedemptions        int64           // redemptions across all users
+		MaxRedemptionsPerUser int64
+		SingleUse             bool // the code can be redeemed once in total
+	}
+
+	// CouponRejection is returned when a valid coupon cannot be applied.
+	// It matches ErrCouponRejected with errors.Is.
+	CouponRejection struct {
+		Code   string
+		Reason RejectionReason
+	}
+)
+
+func (e *CouponRejection) Error() string {
+	return fmt.Sprintf("coupon %s rejected: %s", e.Code, e.Reason)
+}
+
+func (e *CouponRejection) Is(target error) bool {
+	return target == ErrCouponRejected
+}
+
+// NormalizeCouponCode makes codes case and whitespace insensitive
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// PromotionID returns the ID the coupon's promotion gets in a cart
+func (c Coupon) PromotionID() string {
+	if c.Promotion.ID != "" {
+		return c.Promotion.ID
+	}
+	return "coupon:" + NormalizeCouponCode(c.Code)
+}
+
+// CheckRedemption reports whether the coupon may be redeemed again given how many
+// times it has been redeemed in total and by the user
+func (c Coupon) CheckRedemption(total, byUser int64) error {
+	limit := c.MaxRedemptions
+	if c.SingleUse {
+		limit = 1
+	}
+
+	if (limit > 0 && total >= limit) || (c.MaxRedemptionsPerUser > 0 && byUser >= c.MaxRedemptionsPerUser) {
+		return c.reject(RejectExhausted)
+	}
+	return nil
+}
+
+func (c Coupon) reject(reason RejectionReason) error {
+	return &CouponRejection{Code: NormalizeCouponCode(c.Code), Reason: reason}
+}
+
+// CheckCoupon reports whether the coupon can be applied to the cart at the given time.
+// Usage limits are not checked here, see CheckRedemption.
+func (c *Cart) CheckCoupon(coupon Coupon, now time.Time) error {
+	if !coupon.ValidFrom.IsZero() && now.Before(coupon.ValidFrom) {
+		return coupon.reject(RejectNotStarted)
+	}
+	if !coupon.ValidUntil.IsZero() && !now.Before(coupon.ValidUntil) {
+		return coupon.reject(RejectExpired)
+	}
+	if _, ok := c.Coupons[NormalizeCouponCode(coupon.Code)]; ok {
+		return coupon.reject(RejectAlreadyApplied)
+	}
+	if !c.isEligible(coupon.Promotion) {
+		return coupon.reject(RejectNotEligible)
+	}
+	if coupon.MinSpend.IsPositive() && c.CalculateTotalAt(now).LessThan(coupon.MinSpend) {
+		return coupon.reject(RejectMinimumNotMet)
+	}
+
+	return nil
+}
+
+// ApplyCoupon checks the coupon and adds its promotion to the cart
+func (c *Cart) ApplyCoupon(coupon Coupon, now time.Time) error {
+	if err := c.CheckCoupon(coupon, now); err != nil {
+		return err
+	}
+
+	promotion := coupon.Promotion
+	promotion.ID = coupon.PromotionID()
+	c.AddPromotion(promotion)
+
+	if c.Coupons == nil {
+		c.Coupons = make(map[string]string)
+	}
+	c.Coupons[NormalizeCouponCode(coupon.Code)] = promotion.ID
+	return nil
+}
+
+// isEligible reports whether the cart holds what the promotion applies to
+func (c *Cart) isEligible(promotion Promotion) bool {
+	if promotion.IsCartWide() {
+		return len(c.Items) > 0
+	}
+	if promotion.PromotionType == BundlePrice {
+		return promotion.bundleCount(c.quantities()) > 0
+	}
+
+	// Measured lines have no units for the promotions that count them
+	if promotion.SKU != "" {
+		item, ok := c.Items[promotion.SKU]
+		return ok && (item.Quantity > 0 || !promotion.NeedsWholeUnits())
+	}
+	if promotion.NeedsWholeUnits() {
+		return c.quantities()[promotion.ProductID] > 0
+	}
+	return len(c.skusOf(promotion.ProductID)) > 0
+}
+
+// quantities returns the units in the cart per product ID
+func (c *Cart) quantities() map[string]int64 {
+	return c.productQuantities(c.lineQuantities())
+}
+
+// lineQuantities returns the units in the cart per SKU
+func (c *Cart) lineQuantities() map[string]int64 {
+	quantities := make(map[string]int64, len(c.Items))
+	for sku, item := range c.Items {
+		quantities[sku] = item.Quantity
+	}
+	return quantities
+}
+
+// ValidateCoupon validates coupon data
+func ValidateCoupon(coupon Coupon) error {
+	if NormalizeCouponCode(coupon.Code) == "" {
+		return errors.New("coupon code cannot be empty")
+	}
+
+	if err := ValidatePromotion(coupon.Promotion); err != nil {
+		return err
+	}
+
+	if !coupon.ValidFrom.IsZero() && !coupon.ValidUntil.IsZero() && !coupon.ValidUntil.After(coupon.ValidFrom) {
+		return errors.New("coupon must end after it starts")
+	}
+
+	if coupon.MinSpend.IsNegative() {
+		return errors.New("coupon minimum spend cannot be negative")
+	}
+
+	if coupon.MaxRedemptions < 0 || coupon.MaxRedemptionsPerUser < 0 {
+		return errors.New("coupon redemption limits cannot be negative")
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package cart
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCart_ApplyCoupon(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tenOff := Promotion{PromotionType: TotalDiscount, Discount: 10}
+
+	tests := []struct {
+		name       string
+		coupon     Coupon
+		applied    []string
+		wantReason RejectionReason
+		wantTotal  decimal.Decimal
+	}{
+		{
+			name:      "cart-wide coupon",
+			coupon:    Coupon{Code: "save10", Promotion: tenOff},
+			wantTotal: decimal.NewFromFloat(450.00),
+		},
+		{
+			name:      "product coupon",
+			coupon:    Coupon{Code: "HALFA", Promotion: Promotion{ProductID: "A", PromotionType: PercentageDiscount, Discount: 50}},
+			wantTotal: decimal.NewFromFloat(400.00),
+		},
+		{
+			name:      "inside validity window",
+			coupon:    Coupon{Code: "SAVE10", Promotion: tenOff, ValidFrom: now.Add(-time.Hour), ValidUntil: now.Add(time.Hour)},
+			wantTotal: decimal.NewFromFloat(450.00),
+		},
+		{
+			name:       "not started",
+			coupon:     Coupon{Code: "SAVE10", Promotion: tenOff, ValidFrom: now.Add(time.Hour)},
+			wantReason: RejectNotStarted,
+		},
+		{
+			name:       "expired",
+			coupon:     Coupon{Code: "SAVE10", Promotion: tenOff, ValidUntil: now},
+			wantReason: RejectExpired,
+		},
+		{
+			name:       "product not in cart",
+			coupon:     Coupon{Code: "HALFZ", Promotion: Promotion{ProductID: "Z", PromotionType: PercentageDiscount, Discount: 50}},
+			wantReason: RejectNotEligible,
+		},
+		{
+			name:       "minimum not met",
+			coupon:     Coupon{Code: "SAVE10", Promotion: tenOff, MinSpend: decimal.NewFromFloat(1000.00)},
+			wantReason: RejectMinimumNotMet,
+		},
+		{
+			name:       "already applied",
+			coupon:     Coupon{Code: "SAVE10", Promotion: tenOff},
+			applied:    []string{"SAVE10"},
+			wantReason: RejectAlreadyApplied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart()
+			require.NoError(t, cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(100.00)}, 2))
+			require.NoError(t, cart.AddProduct(Product{ID: "B", Price: decimal.NewFromFloat(300.00)}, 1))
+			for _, code := range tt.applied {
+				require.NoError(t, cart.ApplyCoupon(Coupon{Code: code, Promotion: tenOff}, now))
+			}
+			require.NoError(t, ValidateCoupon(tt.coupon))
+
+			err := cart.ApplyCoupon(tt.coupon, now)
+
+			if tt.wantReason != "" {
+				require.ErrorIs(t, err, ErrCouponRejected)
+				var rejection *CouponRejection
+				require.ErrorAs(t, err, &rejection)
+				assert.Equal(t, tt.wantReason, rejection.Reason)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, cart.Coupons, NormalizeCouponCode(tt.coupon.Code))
+			got := cart.CalculateTotal()
+			assert.True(t, tt.wantTotal.Equal(got), "Expected %s, got %s", tt.wantTotal.String(), got.String())
+		})
+	}
+}
+
+func TestCart_RemovePromotion_Coupon(t *testing.T) {
+	cart := NewCart()
+	require.NoError(t, cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(100.00)}, 1))
+	coupon := Coupon{Code: " save10 ", Promotion: Promotion{PromotionType: TotalDiscount, Discount: 10}}
+	require.NoError(t, cart.ApplyCoupon(coupon, time.Now()))
+	assert.Equal(t, "coupon:SAVE10", cart.Coupons["SAVE10"])
+
+	assert.True(t, cart.RemovePromotion("coupon:SAVE10"))
+	assert.Empty(t, cart.Coupons)
+	assert.NoError(t, cart.CheckCoupon(coupon, time.Now()))
+}
+
+func TestCart_RemoveProduct_Coupon(t *testing.T) {
+	cart := NewCart()
+	product := Product{ID: "A", Price: decimal.NewFromFloat(100.00)}
+	require.NoError(t, cart.AddProduct(product, 1))
+	coupon := Coupon{Code: "SAVE10", Promotion: Promotion{ProductID: "A", PromotionType: PercentageDiscount, Discount: 10}}
+	require.NoError(t, cart.ApplyCoupon(coupon, time.Now()))
+
+	// The coupon goes with the promotion it added, so it can be applied again
+	require.NoError(t, cart.RemoveProduct("A"))
+	assert.Empty(t, cart.Coupons)
+	require.NoError(t, cart.AddProduct(product, 1))
+	require.NoError(t, cart.ApplyCoupon(coupon, time.Now()))
+	assert.True(t, decimal.NewFromFloat(90.00).Equal(cart.CalculateTotal()))
+}
+
+func TestCoupon_CheckRedemption(t *testing.T) {
+	tests := []struct {
+		name    string
+		coupon  Coupon
+		total   int64
+		byUser  int64
+		wantErr bool
+	}{
+		{name: "unlimited", coupon: Coupon{}, total: 100, byUser: 100},
+		{name: "below total limit", coupon: Coupon{MaxRedemptions: 3}, total: 2},
+		{name: "total limit reached", coupon: Coupon{MaxRedemptions: 3}, total: 3, wantErr: true},
+		{name: "per user limit reached", coupon: Coupon{MaxRedemptionsPerUser: 1}, total: 5, byUser: 1, wantErr: true},
+		{name: "single use unused", coupon: Coupon{SingleUse: true}},
+		{name: "single use redeemed", coupon: Coupon{SingleUse: true}, total: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.coupon.CheckRedemption(tt.total, tt.byUser)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			var rejection *CouponRejection
+			require.ErrorAs(t, err, &rejection)
+			assert.Equal(t, RejectExhausted, rejection.Reason)
+		})
+	}
+}
+
+func TestValidateCoupon(t *testing.T) {
+	now := time.Now()
+	promotion := Promotion{PromotionType: TotalDiscount, Discount: 10}
+
+	tests := []struct {
+		name     string
+		coupon   Coupon
+		errorMsg string
+	}{
+		{name: "valid coupon", coupon: Coupon{Code: "SAVE10", Promotion: promotion}},
+		{name: "empty code", coupon: Coupon{Code: "  ", Promotion: promotion}, errorMsg: "coupon code cannot be empty"},
+		{name: "invalid promotion", coupon: Coupon{Code: "X", Promotion: Promotion{PromotionType: "unknown"}}, errorMsg: "unknown promotion type"},
+		{name: "ends before it starts", coupon: Coupon{Code: "X", Promotion: promotion, ValidFrom: now, ValidUntil: now}, errorMsg: "coupon must end after it starts"},
+		{name: "negative minimum spend", coupon: Coupon{Code: "X", Promotion: promotion, MinSpend: decimal.NewFromInt(-1)}, errorMsg: "coupon minimum spend cannot be negative"},
+		{name: "negative limit", coupon: Coupon{Code: "X", Promotion: promotion, MaxRedemptionsPerUser: -1}, errorMsg: "coupon redemption limits cannot be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCoupon(tt.coupon)
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.errorMsg, err.Error())
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package cart
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrLimitExceeded = errors.New("purchase limit exceeded")
+
+// ViolationCode tells clients which purchase limit a change would break
+type ViolationCode string
+
+const (
+	ViolationLineQuantity  ViolationCode = "maxLineQuantity" // too many units on one line
+	ViolationLines         ViolationCode = "maxLines"        // too many distinct lines
+	ViolationCustomerLimit ViolationCode = "maxPerCustomer"  // a limited-edition product's per-customer limit
+	ViolationCartTotal     ViolationCode = "maxCartTotal"    // the cart total would exceed its ceiling
+)
+
+type (
+	// Limits caps what a cart may hold, zero values mean no limit. Measured lines
+	// count against MaxLines and MaxTotal only: MaxLineQuantity and a product's
+	// MaxPerCustomer count whole units, which measured lines do not have.
+	Limits struct {
+		MaxLineQuantity int64
+		MaxLines        int
+		MaxTotal        decimal.Decimal // ceiling of CalculateTotal
+	}
+
+	// LimitViolation is returned when a change would break a purchase limit.
+	// It matches ErrLimitExceeded with errors.Is.
+	LimitViolation struct {
+		Code  ViolationCode
+		SKU   string // line that broke the limit, empty for cart-wide limits
+		Limit string // the limit that was reached
+	}
+)
+
+func (e *LimitViolation) Error() string {
+	if e.SKU == "" {
+		return fmt.Sprintf("%s: %s limit is %s", ErrLimitExceeded, e.Code, e.Limit)
+	}
+	return fmt.Sprintf("%s: %s limit for %s is %s", ErrLimitExceeded, e.Code, e.SKU, e.Limit)
+}
+
+func (e *LimitViolation) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// checkLineLimits reports whether the line of product may hold quantity units,
+// given that it holds current units now. A new line also counts against MaxLines.
+func (c *Cart) checkLineLimits(product Product, current, quantity int64) error {
+	sku := product.LineSKU()
+	if _, ok := c.Items[sku]; !ok && c.Limits.MaxLines > 0 && len(c.Items) >= c.Limits.MaxLines {
+		return &LimitViolation{Code: ViolationLines, Limit: fmt.Sprint(c.Limits.MaxLines)}
+	}
+
+	if c.Limits.MaxLineQuantity > 0 && quantity > c.Limits.MaxLineQuantity {
+		return &LimitViolation{Code: ViolationLineQuantity, SKU: sku, Limit: fmt.Sprint(c.Limits.MaxLineQuantity)}
+	}
+
+	// Every variant of the product counts, along with what the customer bought before
+	if product.MaxPerCustomer > 0 {
+		units := c.quantities()[product.ID] - current + quantity + c.Purchased[product.ID]
+		if units > product.MaxPerCustomer {
+			return &LimitViolation{Code: ViolationCustomerLimit, SKU: sku, Limit: fmt.Sprint(product.MaxPerCustomer)}
+		}
+	}
+
+	return nil
+}
+
+// checkTotalLimit reports whether the cart total is within its ceiling
+func (c *Cart) checkTotalLimit() error {
+	if c.Limits.MaxTotal.IsPositive() && c.CalculateTotal().GreaterThan(c.Limits.MaxTotal) {
+		return &LimitViolation{Code: ViolationCartTotal, Limit: DisplayAmount(c.Limits.MaxTotal, c.Currency)}
+	}
+	return nil
+}
+
+// ValidateLimits validates cart limits
+func ValidateLimits(limits Limits) error {
+	if limits.MaxLineQuantity < 0 || limits.MaxLines < 0 || limits.MaxTotal.IsNegative() {
+		return errors.New("cart limits cannot be negative")
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package cart
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCart_AddProduct_Limits(t *testing.T) {
+	limited := Product{ID: "LTD", Price: decimal.NewFromFloat(100.00), MaxPerCustomer: 2}
+
+	tests := []struct {
+		name      string
+		limits    Limits
+		purchased map[string]int64
+		existing  map[string]int64 // product ID -> units already in the cart, priced at 100
+		product   Product
+		quantity  int64
+		wantCode  ViolationCode
+	}{
+		{
+			name:     "within every limit",
+			limits:   Limits{MaxLineQuantity: 5, MaxLines: 2, MaxTotal: decimal.NewFromFloat(1000.00)},
+			existing: map[string]int64{"A": 1},
+			product:  Product{ID: "B", Price: decimal.NewFromFloat(100.00)},
+			quantity: 5,
+		},
+		{
+			name:     "line quantity",
+			limits:   Limits{MaxLineQuantity: 5},
+			existing: map[string]int64{"A": 4},
+			product:  Product{ID: "A", Price: decimal.NewFromFloat(100.00)},
+			quantity: 2,
+			wantCode: ViolationLineQuantity,
+		},
+		{
+			name:     "distinct lines",
+			limits:   Limits{MaxLines: 1},
+			existing: map[string]int64{"A": 1},
+			product:  Product{ID: "B", Price: decimal.NewFromFloat(100.00)},
+			quantity: 1,
+			wantCode: ViolationLines,
+		},
+		{
+			name:     "more units on an existing line do not count as a new line",
+			limits:   Limits{MaxLines: 1},
+			existing: map[string]int64{"A": 1},
+			product:  Product{ID: "A", Price: decimal.NewFromFloat(100.00)},
+			quantity: 1,
+		},
+		{
+			name:     "limited edition",
+			product:  limited,
+			quantity: 3,
+			wantCode: ViolationCustomerLimit,
+		},
+		{
+			name:      "limited edition counts earlier purchases",
+			purchased: map[string]int64{"LTD": 1},
+			product:   limited,
+			quantity:  2,
+			wantCode:  ViolationCustomerLimit,
+		},
+		{
+			name:     "cart total ceiling",
+			limits:   Limits{MaxTotal: decimal.NewFromFloat(500.00)},
+			existing: map[string]int64{"A": 4},
+			product:  Product{ID: "B", Price: decimal.NewFromFloat(100.00)},
+			quantity: 2,
+			wantCode: ViolationCartTotal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart()
+			for productID, qty := range tt.existing {
+				require.NoError(t, cart.AddProduct(Product{ID: productID, Price: decimal.NewFromFloat(100.00)}, qty))
+			}
+			cart.Limits = tt.limits
+			cart.Purchased = tt.purchased
+			before := cart.CalculateTotal()
+
+			err := cart.AddProduct(tt.product, tt.quantity)
+			if tt.wantCode == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var violation *LimitViolation
+			require.ErrorAs(t, err, &violation)
+			assert.ErrorIs(t, err, ErrLimitExceeded)
+			assert.Equal(t, tt.wantCode, violation.Code)
+			// A refused change leaves the cart as it was
+			assert.Len(t, cart.Items, len(tt.existing))
+			assert.True(t, before.Equal(cart.CalculateTotal()))
+		})
+	}
+}
+
+func TestCart_SetQuantity_Limits(t *testing.T) {
+	cart := NewCart()
+	shirt := newShirt()
+	shirt.MaxPerCustomer = 3
+	small, err := shirt.Variant("SHIRT-S")
+	require.NoError(t, err)
+	medium, err := shirt.Variant("SHIRT-M")
+	require.NoError(t, err)
+	require.NoError(t, cart.AddProduct(small, 1))
+	require.NoError(t, cart.AddProduct(medium, 1))
+	cart.Limits = Limits{MaxLineQuantity: 5, MaxTotal: decimal.NewFromFloat(1600.00)}
+
+	// The per-customer limit spans every variant
+	require.NoError(t, cart.SetQuantity("SHIRT-S", 2))
+	err = cart.SetQuantity("SHIRT-S", 3)
+	var violation *LimitViolation
+	require.ErrorAs(t, err, &violation)
+	assert.Equal(t, ViolationCustomerLimit, violation.Code)
+	assert.Equal(t, "SHIRT-S", violation.SKU)
+	assert.Equal(t, int64(2), cart.Items["SHIRT-S"].Quantity)
+
+	cart.Items["SHIRT-M"].Product.MaxPerCustomer = 0
+	cart.Items["SHIRT-S"].Product.MaxPerCustomer = 0
+	err = cart.SetQuantity("SHIRT-M", 2) // 2 * 550 + 2 * 500
+	require.ErrorAs(t, err, &violation)
+	assert.Equal(t, ViolationCartTotal, violation.Code)
+	assert.Equal(t, "1600.00", violation.Limit)
+	assert.Equal(t, int64(1), cart.Items["SHIRT-M"].Quantity)
+}
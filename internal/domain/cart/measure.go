@@ -0,0 +1,206 @@
+package cart
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrWholeUnitPromotion = errors.New("promotion needs products sold by the unit")
+
+// UnitOfMeasure is what a product's Price is charged per
+type UnitOfMeasure string
+
+const (
+	// UnitEach sells whole units counted by CartItem.Quantity. It is the zero value.
+	UnitEach     UnitOfMeasure = "each"
+	UnitKilogram UnitOfMeasure = "kg"
+	UnitGram     UnitOfMeasure = "g"
+	UnitLitre    UnitOfMeasure = "l"
+	UnitMetre    UnitOfMeasure = "m"
+)
+
+// DefaultMeasureStep is the precision of measured amounts when a product has no Step, e.g. one gram per kg
+var DefaultMeasureStep = decimal.New(1, -3)
+
+// IsMeasured reports whether the product is sold in decimal amounts of a unit of measure
+func (p Product) IsMeasured() bool {
+	return p.Unit != "" && p.Unit != UnitEach
+}
+
+// GetDiscountedMeasurePrice prices a measured amount and then applies the product discount
+func (p Product) GetDiscountedMeasurePrice(measure decimal.Decimal) decimal.Decimal {
+	return p.applyDiscount(p.Price.Mul(measure))
+}
+
+func (p Product) measureStep() decimal.Decimal {
+	if p.Step.IsPositive() {
+		return p.Step
+	}
+	return DefaultMeasureStep
+}
+
+// NeedsWholeUnits reports whether the promotion counts units, so it cannot price measured products
+func (p *Promotion) NeedsWholeUnits() bool {
+	switch p.PromotionType {
+	case Buy1Get1Free, BuyXGetY, BundlePrice:
+		return true
+	}
+	return false
+}
+
+// CalculateMeasuredPrice prices a measured amount at the unit price, see CalculatePrice.
+// Promotions that count units fail with ErrWholeUnitPromotion.
+func (p *Promotion) CalculateMeasuredPrice(price, measure decimal.Decimal) (decimal.Decimal, error) {
+	if p.NeedsWholeUnits() {
+		return decimal.Zero, fmt.Errorf("%w: %s", ErrWholeUnitPromotion, p.PromotionType)
+	}
+
+	line := lineContext{measure: measure}
+	return p.applyToLine(price.Mul(measure), line), nil
+}
+
+// AddMeasure adds an amount of a measured product, e.g. 1.25 kg, to the line of its SKU.
+// The amount must respect the product's MinMeasure and Step. Of the cart's Limits,
+// MaxLines and MaxTotal apply.
+func (c *Cart) AddMeasure(product Product, measure decimal.Decimal) error {
+	if err := ValidateProduct(product); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidProduct, err)
+	}
+	if len(product.Variants) > 0 {
+		return fmt.Errorf("%w: product %s has variants, add one of its SKUs", ErrInvalidProduct, product.ID)
+	}
+	if !product.IsMeasured() {
+		return fmt.Errorf("%w: product %s is sold by the unit", ErrInvalidQuantity, product.ID)
+	}
+
+	product.Currency = strings.ToUpper(product.Currency)
+	if product.Currency != "" && c.Currency != "" && product.Currency != c.Currency {
+		return fmt.Errorf("%w: cart is in %s, product %s is in %s", ErrCurrencyMismatch, c.Currency, product.ID, product.Currency)
+	}
+
+	sku := product.LineSKU()
+	item, ok := c.Items[sku]
+	total := measure
+	if ok {
+		total = total.Add(item.Measure)
+	}
+	if err := ValidateMeasure(product, total); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidQuantity, err)
+	}
+	// A line of no units only meets MaxLines
+	if err := c.checkLineLimits(product, 0, 0); err != nil {
+		return err
+	}
+
+	currency := c.Currency
+	if c.Currency == "" {
+		c.Currency = product.Currency
+	}
+	if ok {
+		item.Measure = total
+	} else {
+		c.Items[sku] = &CartItem{Product: product, Measure: measure}
+	}
+
+	if err := c.checkTotalLimit(); err != nil {
+		c.Currency = currency
+		if ok {
+			item.Measure = total.Sub(measure)
+		} else {
+			delete(c.Items, sku)
+		}
+		return err
+	}
+	return nil
+}
+
+// SetMeasure replaces the amount of a measured line that is already in the cart.
+// Of the cart's Limits, only MaxTotal applies.
+func (c *Cart) SetMeasure(sku string, measure decimal.Decimal) error {
+	item, ok := c.Items[sku]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, sku)
+	}
+	if !item.Product.IsMeasured() {
+		return fmt.Errorf("%w: product %s is sold by the unit", ErrInvalidQuantity, item.Product.ID)
+	}
+	if err := ValidateMeasure(item.Product, measure); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidQuantity, err)
+	}
+
+	previous := item.Measure
+	item.Measure = measure
+	if err := c.checkTotalLimit(); err != nil {
+		item.Measure = previous
+		return err
+	}
+	return nil
+}
+
+// CheckPromotion reports whether the promotion can price the lines in the cart.
+// Promotions that count units fail with ErrWholeUnitPromotion when a product they
+// name is measured.
+func (c *Cart) CheckPromotion(promotion Promotion) error {
+	if !promotion.NeedsWholeUnits() {
+		return nil
+	}
+
+	productIDs := []string{promotion.ProductID, promotion.BuyProductID}
+	for _, item := range promotion.BundleItems {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	for _, item := range c.Items {
+		if !item.Product.IsMeasured() {
+			continue
+		}
+		for _, productID := range productIDs {
+			if productID != "" && productID == item.Product.ID {
+				return fmt.Errorf("%w: %s is sold by %s", ErrWholeUnitPromotion, productID, item.Product.Unit)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateMeasure validates a measured amount of the product
+func ValidateMeasure(product Product, measure decimal.Decimal) error {
+	if !measure.IsPositive() {
+		return errors.New("measure must be positive")
+	}
+	if product.MinMeasure.IsPositive() && measure.LessThan(product.MinMeasure) {
+		return fmt.Errorf("measure must be at least %s %s", product.MinMeasure, product.Unit)
+	}
+	if step := product.measureStep(); !measure.Mod(step).IsZero() {
+		return fmt.Errorf("measure must be a multiple of %s %s", step, product.Unit)
+	}
+
+	return nil
+}
+
+func validateUnit(product Product) error {
+	switch product.Unit {
+	case "", UnitEach:
+		if !product.MinMeasure.IsZero() || !product.Step.IsZero() {
+			return errors.New("only measured products can have a minimum measure or step")
+		}
+		return nil
+	case UnitKilogram, UnitGram, UnitLitre, UnitMetre:
+	default:
+		return errors.New("unknown product unit of measure")
+	}
+
+	if product.MinMeasure.IsNegative() || product.Step.IsNegative() {
+		return errors.New("product measure and step cannot be negative")
+	}
+	if len(product.PriceTiers) > 0 {
+		return errors.New("price tiers need a product sold by the unit")
+	}
+	if product.MaxPerCustomer > 0 {
+		return errors.New("purchase limits need a product sold by the unit")
+	}
+
+	return nil
+}
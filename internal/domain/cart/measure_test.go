@@ -0,0 +1,263 @@
+package cart
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCoffee returns coffee beans at 800 per kg, sold from 0.25 kg in 50 g steps
+func newCoffee() Product {
+	return Product{
+		ID:         "COFFEE",
+		Price:      decimal.NewFromFloat(800.00),
+		Unit:       UnitKilogram,
+		MinMeasure: decimal.NewFromFloat(0.25),
+		Step:       decimal.NewFromFloat(0.05),
+	}
+}
+
+func TestCart_AddMeasure(t *testing.T) {
+	tests := []struct {
+		name     string
+		product  Product
+		measures []string
+		want     decimal.Decimal
+		errorMsg string
+	}{
+		{
+			name:     "priced per unit of measure",
+			product:  newCoffee(),
+			measures: []string{"1.25"},
+			want:     decimal.NewFromFloat(1000.00),
+		},
+		{
+			name:     "amounts add up on one line",
+			product:  newCoffee(),
+			measures: []string{"0.25", "0.5"},
+			want:     decimal.NewFromFloat(600.00),
+		},
+		{
+			name:     "product discount",
+			product:  func() Product { p := newCoffee(); p.Discount = 10; return p }(),
+			measures: []string{"0.5"},
+			want:     decimal.NewFromFloat(360.00),
+		},
+		{
+			name:     "default step",
+			product:  Product{ID: "CABLE", Price: decimal.NewFromFloat(12.00), Unit: UnitMetre},
+			measures: []string{"3.5"},
+			want:     decimal.NewFromFloat(42.00),
+		},
+		{
+			name:     "below the minimum",
+			product:  newCoffee(),
+			measures: []string{"0.2"},
+			errorMsg: "invalid quantity: measure must be at least 0.25 kg",
+		},
+		{
+			name:     "off the step",
+			product:  newCoffee(),
+			measures: []string{"0.33"},
+			errorMsg: "invalid quantity: measure must be a multiple of 0.05 kg",
+		},
+		{
+			name:     "not positive",
+			product:  newCoffee(),
+			measures: []string{"0"},
+			errorMsg: "invalid quantity: measure must be positive",
+		},
+		{
+			name:     "product sold by the unit",
+			product:  Product{ID: "A", Price: decimal.NewFromFloat(100.00)},
+			measures: []string{"1.5"},
+			errorMsg: "invalid quantity: product A is sold by the unit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart()
+			var err error
+			for _, measure := range tt.measures {
+				if err = cart.AddMeasure(tt.product, decimal.RequireFromString(measure)); err != nil {
+					break
+				}
+			}
+
+			if tt.errorMsg != "" {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, ErrInvalidQuantity)
+				assert.Equal(t, tt.errorMsg, err.Error())
+				assert.Empty(t, cart.Items)
+				return
+			}
+			require.NoError(t, err)
+			got := cart.CalculateTotal()
+			assert.True(t, tt.want.Equal(got), "Expected %s, got %s", tt.want.String(), got.String())
+		})
+	}
+}
+
+func TestCart_Measure_Limits(t *testing.T) {
+	cart := NewCart()
+	cart.Limits = Limits{MaxLineQuantity: 1, MaxLines: 1}
+
+	// MaxLineQuantity counts units, which measured lines do not have
+	require.NoError(t, cart.AddMeasure(newCoffee(), decimal.NewFromFloat(2.5)))
+	require.NoError(t, cart.AddMeasure(newCoffee(), decimal.NewFromFloat(0.5)))
+	require.NoError(t, cart.SetMeasure("COFFEE", decimal.NewFromFloat(4.0)))
+	assert.True(t, decimal.NewFromFloat(4.0).Equal(cart.Items["COFFEE"].Measure))
+
+	var violation *LimitViolation
+	cable := Product{ID: "CABLE", Price: decimal.NewFromFloat(12.00), Unit: UnitMetre}
+	require.ErrorAs(t, cart.AddMeasure(cable, decimal.NewFromFloat(3.5)), &violation)
+	assert.Equal(t, ViolationLines, violation.Code)
+	assert.NotContains(t, cart.Items, "CABLE")
+}
+
+func TestCart_SetMeasure(t *testing.T) {
+	cart := NewCart()
+	require.NoError(t, cart.AddMeasure(newCoffee(), decimal.NewFromFloat(0.5)))
+	require.NoError(t, cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(100.00)}, 1))
+
+	require.NoError(t, cart.SetMeasure("COFFEE", decimal.NewFromFloat(1.5)))
+	assert.True(t, decimal.NewFromFloat(1300.00).Equal(cart.CalculateTotal()))
+
+	err := cart.SetMeasure("COFFEE", decimal.NewFromFloat(0.1))
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+	assert.True(t, decimal.NewFromFloat(1.5).Equal(cart.Items["COFFEE"].Measure))
+
+	assert.ErrorIs(t, cart.SetMeasure("A", decimal.NewFromFloat(1.5)), ErrInvalidQuantity)
+	assert.ErrorIs(t, cart.SetQuantity("COFFEE", 2), ErrInvalidQuantity)
+	assert.ErrorIs(t, cart.AddProduct(newCoffee(), 2), ErrInvalidQuantity)
+	assert.ErrorIs(t, cart.SetMeasure("MISSING", decimal.NewFromFloat(1.5)), ErrProductNotFound)
+
+	cart.Limits = Limits{MaxTotal: decimal.NewFromFloat(2000.00)}
+	var violation *LimitViolation
+	require.ErrorAs(t, cart.SetMeasure("COFFEE", decimal.NewFromFloat(2.5)), &violation)
+	assert.Equal(t, ViolationCartTotal, violation.Code)
+	assert.True(t, decimal.NewFromFloat(1.5).Equal(cart.Items["COFFEE"].Measure))
+}
+
+func TestCart_Breakdown_Measured(t *testing.T) {
+	cart := NewCart()
+	require.NoError(t, cart.AddMeasure(newCoffee(), decimal.NewFromFloat(1.25)))
+	cart.AddPromotion(Promotion{ID: "BOGO", ProductID: "COFFEE", PromotionType: Buy1Get1Free})
+	cart.AddPromotion(Promotion{ID: "OFF", ProductID: "COFFEE", PromotionType: FixedAmountOff, Amount: decimal.NewFromFloat(40.00), Stacking: Stackable})
+
+	got := cart.Breakdown()
+
+	require.Len(t, got.Lines, 1)
+	line := got.Lines[0]
+	assert.Equal(t, int64(0), line.Quantity)
+	assert.True(t, decimal.NewFromFloat(1.25).Equal(line.Measure))
+	assert.Equal(t, UnitKilogram, line.Unit)
+	// BOGO counts units and is skipped, the fixed amount is taken off each kg
+	require.Len(t, line.Promotions, 1)
+	assert.Equal(t, "OFF", line.Promotions[0].PromotionID)
+	assert.True(t, decimal.NewFromFloat(50.00).Equal(line.Promotions[0].Amount))
+	assert.True(t, decimal.NewFromFloat(950.00).Equal(got.Total))
+
+	cart.Strategy = BestDeal
+	decisions := cart.OptimizePromotions()
+	require.Len(t, decisions, 2)
+	for _, decision := range decisions[0].Decisions {
+		if decision.PromotionID == "BOGO" {
+			assert.False(t, decision.Chosen)
+			assert.Equal(t, ReasonWholeUnits, decision.Reason)
+		}
+	}
+	assert.True(t, decimal.NewFromFloat(950.00).Equal(cart.CalculateTotal()))
+}
+
+func TestCart_CheckPromotion_Measured(t *testing.T) {
+	cart := NewCart()
+	require.NoError(t, cart.AddMeasure(newCoffee(), decimal.NewFromFloat(0.5)))
+
+	tests := []struct {
+		name      string
+		promotion Promotion
+		wantErr   bool
+	}{
+		{name: "percentage", promotion: Promotion{ProductID: "COFFEE", PromotionType: PercentageDiscount, Discount: 10}},
+		{name: "buy one get one", promotion: Promotion{ProductID: "COFFEE", PromotionType: Buy1Get1Free}, wantErr: true},
+		{name: "buy X get Y qualifying product", promotion: Promotion{ProductID: "MUG", BuyProductID: "COFFEE", PromotionType: BuyXGetY}, wantErr: true},
+		{name: "bundle", promotion: Promotion{PromotionType: BundlePrice, BundleItems: []BundleItem{{ProductID: "COFFEE", Quantity: 1}}}, wantErr: true},
+		{name: "other product", promotion: Promotion{ProductID: "MUG", PromotionType: Buy1Get1Free}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := cart.CheckPromotion(tt.promotion)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrWholeUnitPromotion)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPromotion_CalculateMeasuredPrice(t *testing.T) {
+	price := decimal.NewFromFloat(800.00)
+	measure := decimal.NewFromFloat(0.75)
+
+	percentage := Promotion{PromotionType: PercentageDiscount, Discount: 25}
+	got, err := percentage.CalculateMeasuredPrice(price, measure)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromFloat(450.00).Equal(got), "got %s", got.String())
+
+	bogo := Promotion{PromotionType: Buy1Get1Free}
+	_, err = bogo.CalculateMeasuredPrice(price, measure)
+	assert.ErrorIs(t, err, ErrWholeUnitPromotion)
+}
+
+func TestValidateProduct_Measured(t *testing.T) {
+	tests := []struct {
+		name     string
+		product  Product
+		errorMsg string
+	}{
+		{name: "measured", product: newCoffee()},
+		{
+			name:     "unknown unit",
+			product:  Product{ID: "A", Unit: "bushel"},
+			errorMsg: "unknown product unit of measure",
+		},
+		{
+			name:     "step on a product sold by the unit",
+			product:  Product{ID: "A", Step: decimal.NewFromFloat(0.5)},
+			errorMsg: "only measured products can have a minimum measure or step",
+		},
+		{
+			name:     "negative step",
+			product:  Product{ID: "A", Unit: UnitGram, Step: decimal.NewFromInt(-1)},
+			errorMsg: "product measure and step cannot be negative",
+		},
+		{
+			name:     "price tiers",
+			product:  Product{ID: "A", Unit: UnitLitre, PriceTiers: []PriceTier{{MinQuantity: 2, UnitPrice: decimal.NewFromInt(1)}}},
+			errorMsg: "price tiers need a product sold by the unit",
+		},
+		{
+			name:     "purchase limit",
+			product:  Product{ID: "A", Unit: UnitLitre, MaxPerCustomer: 2},
+			errorMsg: "purchase limits need a product sold by the unit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProduct(tt.product)
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.errorMsg, err.Error())
+		})
+	}
+}
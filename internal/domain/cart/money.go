@@ -0,0 +1,213 @@
+package cart
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrUnknownCurrency     = errors.New("unknown currency")
+	ErrCurrencyMismatch    = errors.New("currency mismatch")
+	ErrUnknownRoundingMode = errors.New("unknown rounding mode")
+)
+
+// defaultDecimals is the precision of amounts whose currency is not set
+const defaultDecimals = 2
+
+// RoundingMode selects how amounts are rounded to the currency precision
+type RoundingMode string
+
+const (
+	// RoundHalfUp rounds halves away from zero, 0.125 -> 0.13. It is the zero value.
+	RoundHalfUp RoundingMode = "halfUp"
+	// RoundHalfEven rounds halves to the even neighbour, 0.125 -> 0.12.
+	RoundHalfEven RoundingMode = "halfEven"
+	// RoundBankers is the usual name of RoundHalfEven.
+	RoundBankers = RoundHalfEven
+)
+
+type (
+	// Currency describes an ISO 4217 currency
+	Currency struct {
+		Code     string
+		Decimals int32 // minor unit digits, 0 for JPY and 3 for KWD
+		Symbol   string
+	}
+
+	// Money pairs an amount with its ISO 4217 currency code
+	Money struct {
+		Amount   decimal.Decimal
+		Currency string
+	}
+
+	// Locale describes how amounts are written in a locale
+	Locale struct {
+		Decimal     string
+		Group       string
+		SymbolFirst bool // "$1.00" rather than "1,00 €"
+		Space       bool // space between the amount and the symbol
+	}
+)
+
+var currencies = map[string]Currency{
+	"THB": {Code: "THB", Decimals: 2, Symbol: "฿"},
+	"USD": {Code: "USD", Decimals: 2, Symbol: "$"},
+	"EUR": {Code: "EUR", Decimals: 2, Symbol: "€"},
+	"GBP": {Code: "GBP", Decimals: 2, Symbol: "£"},
+	"SGD": {Code: "SGD", Decimals: 2, Symbol: "S$"},
+	"JPY": {Code: "JPY", Decimals: 0, Symbol: "¥"},
+	"KRW": {Code: "KRW", Decimals: 0, Symbol: "₩"},
+	"KWD": {Code: "KWD", Decimals: 3, Symbol: "KD"},
+	"BHD": {Code: "BHD", Decimals: 3, Symbol: "BD"},
+}
+
+var locales = map[string]Locale{
+	"en-US": {Decimal: ".", Group: ",", SymbolFirst: true},
+	"en-GB": {Decimal: ".", Group: ",", SymbolFirst: true},
+	"th-TH": {Decimal: ".", Group: ",", SymbolFirst: true},
+	"ja-JP": {Decimal: ".", Group: ",", SymbolFirst: true},
+	"de-DE": {Decimal: ",", Group: ".", Space: true},
+	"fr-FR": {Decimal: ",", Group: " ", Space: true},
+}
+
+// LookupCurrency returns the currency with the given ISO 4217 code
+func LookupCurrency(code string) (Currency, error) {
+	currency, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %s", ErrUnknownCurrency, code)
+	}
+	return currency, nil
+}
+
+// LookupLocale returns the formatting rules of a BCP 47 locale such as "th-TH".
+// Unknown locales fall back to en-US.
+func LookupLocale(tag string) Locale {
+	if locale, ok := locales[tag]; ok {
+		return locale
+	}
+	return locales["en-US"]
+}
+
+// decimalsOf returns the precision of the currency, unknown or unset currencies use two digits
+func decimalsOf(code string) int32 {
+	if currency, err := LookupCurrency(code); err == nil {
+		return currency.Decimals
+	}
+	return defaultDecimals
+}
+
+// ValidateRoundingMode validates a rounding mode, the empty mode rounds half up
+func ValidateRoundingMode(mode RoundingMode) error {
+	switch mode {
+	case "", RoundHalfUp, RoundHalfEven:
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownRoundingMode, mode)
+}
+
+// Round rounds the amount to places digits. Modes that ValidateRoundingMode
+// refuses round half up.
+func (m RoundingMode) Round(amount decimal.Decimal, places int32) decimal.Decimal {
+	if m == RoundHalfEven {
+		return amount.RoundBank(places)
+	}
+	return amount.Round(places)
+}
+
+func NewMoney(amount decimal.Decimal, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// Add sums two amounts of the same currency
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return Money{Amount: m.Amount.Add(other.Amount), Currency: m.Currency}, nil
+}
+
+// Sub subtracts an amount of the same currency
+func (m Money) Sub(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return Money{Amount: m.Amount.Sub(other.Amount), Currency: m.Currency}, nil
+}
+
+// Round rounds the amount to the minor unit of its currency
+func (m Money) Round(mode RoundingMode) Money {
+	return Money{Amount: mode.Round(m.Amount, decimalsOf(m.Currency)), Currency: m.Currency}
+}
+
+// String renders the amount with the digits of its currency, e.g. "1234.50 THB"
+func (m Money) String() string {
+	return strings.TrimSpace(DisplayAmount(m.Amount, m.Currency) + " " + m.Currency)
+}
+
+// Format renders the amount for a locale with grouping and the currency symbol,
+// e.g. "฿1,234.50" for th-TH or "1.234,50 €" for de-DE
+func (m Money) Format(localeTag string) string {
+	locale := LookupLocale(localeTag)
+	symbol := m.Currency
+	if currency, err := LookupCurrency(m.Currency); err == nil {
+		symbol = currency.Symbol
+	}
+
+	digits := DisplayAmount(m.Amount.Abs(), m.Currency)
+	whole, fraction, _ := strings.Cut(digits, ".")
+	number := groupDigits(whole, locale.Group)
+	if fraction != "" {
+		number += locale.Decimal + fraction
+	}
+
+	sep := ""
+	if locale.Space {
+		sep = " "
+	}
+	formatted := number + sep + symbol
+	if locale.SymbolFirst {
+		formatted = symbol + sep + number
+	}
+	if m.Amount.IsNegative() {
+		formatted = "-" + formatted
+	}
+	return formatted
+}
+
+func groupDigits(whole, group string) string {
+	if len(whole) <= 3 {
+		return whole
+	}
+
+	var b strings.Builder
+	head := len(whole) % 3
+	if head > 0 {
+		b.WriteString(whole[:head])
+	}
+	for i := head; i < len(whole); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(group)
+		}
+		b.WriteString(whole[i : i+3])
+	}
+	return b.String()
+}
+
+// DisplayAmount renders the amount with the digits of the currency, two when it is unset
+func DisplayAmount(amount decimal.Decimal, currency string) string {
+	return amount.StringFixed(decimalsOf(currency))
+}
+
+// ValidateCurrency validates an ISO 4217 code, the empty code is allowed
+func ValidateCurrency(code string) error {
+	if code == "" {
+		return nil
+	}
+	if _, err := LookupCurrency(code); err != nil {
+		return errors.New("unknown currency code")
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package cart
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoney_Add(t *testing.T) {
+	got, err := NewMoney(decimal.NewFromFloat(1.10), "thb").Add(NewMoney(decimal.NewFromFloat(2.20), "THB"))
+	require.NoError(t, err)
+	assert.Equal(t, "THB", got.Currency)
+	assert.True(t, decimal.NewFromFloat(3.30).Equal(got.Amount))
+
+	_, err = NewMoney(decimal.NewFromInt(1), "THB").Add(NewMoney(decimal.NewFromInt(1), "USD"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = NewMoney(decimal.NewFromInt(1), "THB").Sub(NewMoney(decimal.NewFromInt(1), "JPY"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestMoney_Round(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		currency string
+		mode     RoundingMode
+		want     string
+	}{
+		{name: "half up", amount: "0.125", currency: "USD", mode: RoundHalfUp, want: "0.13"},
+		{name: "half even", amount: "0.125", currency: "USD", mode: RoundHalfEven, want: "0.12"},
+		{name: "bankers", amount: "0.135", currency: "USD", mode: RoundBankers, want: "0.14"},
+		{name: "yen has no minor unit", amount: "100.5", currency: "JPY", mode: RoundHalfUp, want: "101"},
+		{name: "yen half even", amount: "100.5", currency: "JPY", mode: RoundHalfEven, want: "100"},
+		{name: "dinar has three digits", amount: "1.2345", currency: "KWD", mode: RoundHalfUp, want: "1.235"},
+		{name: "unset currency uses two digits", amount: "1.005", currency: "", mode: RoundHalfUp, want: "1.01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewMoney(decimal.RequireFromString(tt.amount), tt.currency).Round(tt.mode)
+			assert.True(t, decimal.RequireFromString(tt.want).Equal(got.Amount), "Expected %s, got %s", tt.want, got.Amount)
+		})
+	}
+}
+
+func TestMoney_Format(t *testing.T) {
+	tests := []struct {
+		name   string
+		money  Money
+		locale string
+		want   string
+	}{
+		{name: "thai baht", money: NewMoney(decimal.NewFromFloat(1234.5), "THB"), locale: "th-TH", want: "฿1,234.50"},
+		{name: "yen", money: NewMoney(decimal.NewFromInt(1234567), "JPY"), locale: "ja-JP", want: "¥1,234,567"},
+		{name: "euro in germany", money: NewMoney(decimal.NewFromFloat(1234.5), "EUR"), locale: "de-DE", want: "1.234,50 €"},
+		{name: "dinar", money: NewMoney(decimal.NewFromFloat(12.3456), "KWD"), locale: "en-US", want: "KD12.346"},
+		{name: "negative", money: NewMoney(decimal.NewFromInt(-5), "USD"), locale: "en-US", want: "-$5.00"},
+		{name: "unknown locale", money: NewMoney(decimal.NewFromInt(1000), "USD"), locale: "xx", want: "$1,000.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.money.Format(tt.locale))
+		})
+	}
+
+	assert.Equal(t, "1234 JPY", NewMoney(decimal.NewFromFloat(1234.4), "JPY").String())
+	assert.Equal(t, "1.500 KWD", NewMoney(decimal.NewFromFloat(1.5), "KWD").String())
+}
+
+func TestCart_AddProduct_Currency(t *testing.T) {
+	cart := NewCart()
+	require.NoError(t, cart.AddProduct(Product{ID: "A", Price: decimal.NewFromInt(100)}, 1))
+	assert.Equal(t, "", cart.Currency)
+
+	require.NoError(t, cart.AddProduct(Product{ID: "B", Price: decimal.NewFromInt(500), Currency: "jpy"}, 1))
+	assert.Equal(t, "JPY", cart.Currency)
+
+	err := cart.AddProduct(Product{ID: "C", Price: decimal.NewFromInt(5), Currency: "USD"}, 1)
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	err = cart.AddProduct(Product{ID: "D", Price: decimal.NewFromInt(5), Currency: "XYZ"}, 1)
+	assert.ErrorIs(t, err, ErrInvalidProduct)
+
+	total, err := cart.TotalMoney()
+	require.NoError(t, err)
+	assert.Equal(t, "600 JPY", total.String())
+
+	cart.Items["E"] = &CartItem{Product: Product{ID: "E", Currency: "USD"}, Quantity: 1}
+	_, err = cart.TotalMoney()
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestCart_Breakdown_Rounding(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		mode     RoundingMode
+		want     decimal.Decimal
+	}{
+		{name: "yen half up", currency: "JPY", mode: RoundHalfUp, want: decimal.NewFromInt(2)},     // 1 + round(0.5)
+		{name: "yen half even", currency: "JPY", mode: RoundHalfEven, want: decimal.NewFromInt(1)}, // 1 + round(0.5)
+		{name: "baht", currency: "THB", mode: RoundHalfUp, want: decimal.NewFromFloat(1.50)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart()
+			require.NoError(t, cart.SetRounding(tt.mode))
+			require.NoError(t, cart.AddProduct(Product{ID: "A", Price: decimal.NewFromInt(1), Currency: tt.currency}, 1))
+			require.NoError(t, cart.AddProduct(Product{ID: "B", Price: decimal.NewFromInt(1), Discount: 50}, 1))
+
+			got := cart.Breakdown()
+
+			assert.Equal(t, tt.currency, got.Currency)
+			assert.True(t, tt.want.Equal(got.Total), "Expected %s, got %s", tt.want.String(), got.Total.String())
+		})
+	}
+}
+
+func TestCart_SetRounding(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    RoundingMode
+		want    RoundingMode
+		wantErr error
+	}{
+		{name: "half up", mode: RoundHalfUp, want: RoundHalfUp},
+		{name: "bankers", mode: RoundBankers, want: RoundHalfEven},
+		{name: "default", mode: "", want: ""},
+		{name: "unknown mode", mode: "halfDown", want: RoundHalfEven, wantErr: ErrUnknownRoundingMode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart()
+			cart.Rounding = RoundHalfEven
+
+			err := cart.SetRounding(tt.mode)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, cart.Rounding)
+		})
+	}
+}
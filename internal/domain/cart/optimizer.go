@@ -0,0 +1,184 @@
+package cart
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// PromotionStrategy selects how a cart picks among its promotions
+type PromotionStrategy string
+
+const (
+	// PriorityOrder applies promotions by priority and stacking rule. It is the zero value.
+	PriorityOrder PromotionStrategy = "priorityOrder"
+	// BestDeal applies the valid combination that is cheapest for the customer.
+	BestDeal PromotionStrategy = "bestDeal"
+)
+
+// DecisionReason explains why the optimizer did or did not choose a promotion
+type DecisionReason string
+
+const (
+	ReasonCheapest          DecisionReason = "part of the cheapest combination"
+	ReasonNoSaving          DecisionReason = "does not lower the price"
+	ReasonExcluded          DecisionReason = "excluded by the chosen exclusive promotion"
+	ReasonExclusiveConflict DecisionReason = "exclusive and a cheaper combination exists"
+	ReasonNotCheapest       DecisionReason = "a cheaper combination exists without it"
+	ReasonInactive          DecisionReason = "not active at the pricing time"
+	ReasonWholeUnits        DecisionReason = "needs products sold by the unit"
+)
+
+// maxStackableSubsets caps the stackable promotions whose subsets are enumerated.
+// Larger scopes only compare every stackable promotion together against each exclusive one.
+const maxStackableSubsets = 10
+
+type (
+	// PromotionDecision records the optimizer outcome for a single promotion
+	PromotionDecision struct {
+		PromotionID   string
+		PromotionType PromotionType
+		Chosen        bool
+		Reason        DecisionReason
+	}
+
+	// ScopeDecision is the optimizer outcome for one cart line, or for the cart when ProductID is empty
+	ScopeDecision struct {
+		ProductID string
+		SKU       string
+		Before    decimal.Decimal
+		Total     decimal.Decimal
+		Savings   []PromotionSaving
+		Decisions []PromotionDecision
+	}
+)
+
+// OptimizePromotions reports, per cart line and for the cart, which promotions give
+// the customer the lowest price and why the others were left out.
+// Bundles are always formed first, products are then optimized on their leftover
+// units and the cart-wide promotions are optimized against the resulting subtotal.
+// Totals are rounded to the cart's currency like the ones Breakdown reports.
+func (c *Cart) OptimizePromotions() []ScopeDecision {
+	skus := make([]string, 0, len(c.Items))
+	for sku := range c.Items {
+		skus = append(skus, sku)
+	}
+	sort.Strings(skus)
+
+	decisions := make([]ScopeDecision, 0, len(skus)+1)
+	subtotal := decimal.Zero
+	at := c.now()
+	bundles, remaining := c.applyBundles(at)
+	for _, bundle := range bundles {
+		subtotal = subtotal.Add(bundle.Total)
+	}
+
+	for _, sku := range skus {
+		item := c.Items[sku]
+		// Lines are priced and rounded as BreakdownAt does, so the subtotal the
+		// cart-wide promotions see is the one the cart charges
+		line, scope := c.unpromotedLine(item, remaining, at)
+		decision := optimizePromotions(c.promotionsFor(item.Product), line.LineTotal, scope)
+		decision.Total = c.round(decision.Total)
+		decision.ProductID = item.Product.ID
+		decision.SKU = sku
+		decisions = append(decisions, decision)
+		subtotal = subtotal.Add(decision.Total)
+	}
+
+	decision := optimizePromotions(c.CartPromotions, subtotal, lineContext{at: at})
+	decision.Total = c.round(decision.Total)
+	return append(decisions, decision)
+}
+
+// evaluatePromotions prices one scope using the cart's promotion strategy
+func (c *Cart) evaluatePromotions(promotions []*Promotion, lineTotal decimal.Decimal, line lineContext) (decimal.Decimal, []PromotionSaving) {
+	if c.Strategy == BestDeal {
+		decision := optimizePromotions(promotions, lineTotal, line)
+		return decision.Total, decision.Savings
+	}
+
+	return applyPromotions(promotions, lineTotal, line)
+}
+
+// optimizePromotions tries every valid combination in a scope: no promotion, each
+// exclusive promotion alone and each subset of the stackable ones. Ties go to the
+// combination with fewer promotions, then to the one enumerated first.
+func optimizePromotions(promotions []*Promotion, lineTotal decimal.Decimal, line lineContext) ScopeDecision {
+	sorted := sortPromotions(promotions)
+
+	var exclusives, stackables []*Promotion
+	for _, promo := range sorted {
+		if !promo.appliesTo(line) {
+			continue
+		}
+		if promo.IsExclusive() {
+			exclusives = append(exclusives, promo)
+		} else {
+			stackables = append(stackables, promo)
+		}
+	}
+
+	candidates := [][]*Promotion{nil}
+	for _, promo := range exclusives {
+		candidates = append(candidates, []*Promotion{promo})
+	}
+	if len(stackables) > maxStackableSubsets {
+		candidates = append(candidates, stackables)
+	} else {
+		for mask := 1; mask < 1<<len(stackables); mask++ {
+			var subset []*Promotion
+			for i, promo := range stackables {
+				if mask&(1<<i) != 0 {
+					subset = append(subset, promo)
+				}
+			}
+			candidates = append(candidates, subset)
+		}
+	}
+
+	best := ScopeDecision{Before: lineTotal, Total: lineTotal}
+	var bestSet []*Promotion
+	for _, candidate := range candidates {
+		total, savings := applyPromotions(candidate, lineTotal, line)
+		if total.LessThan(best.Total) || (total.Equal(best.Total) && len(candidate) < len(bestSet)) {
+			best.Total = total
+			best.Savings = savings
+			bestSet = candidate
+		}
+	}
+
+	chosen := make(map[*Promotion]bool, len(bestSet))
+	exclusiveChosen := false
+	for _, promo := range bestSet {
+		chosen[promo] = true
+		exclusiveChosen = exclusiveChosen || promo.IsExclusive()
+	}
+
+	for _, promo := range sorted {
+		decision := PromotionDecision{
+			PromotionID:   promo.ID,
+			PromotionType: promo.PromotionType,
+			Chosen:        chosen[promo],
+		}
+		switch {
+		case decision.Chosen:
+			decision.Reason = ReasonCheapest
+		case !promo.IsActiveAt(line.at):
+			decision.Reason = ReasonInactive
+		case !promo.appliesTo(line):
+			decision.Reason = ReasonWholeUnits
+		case !promo.applyToLine(lineTotal, line).LessThan(lineTotal):
+			decision.Reason = ReasonNoSaving
+		case exclusiveChosen:
+			decision.Reason = ReasonExcluded
+		case promo.IsExclusive():
+			decision.Reason = ReasonExclusiveConflict
+		default:
+			decision.Reason = ReasonNotCheapest
+		}
+		best.Decisions = append(best.Decisions, decision)
+	}
+
+	return best
+}
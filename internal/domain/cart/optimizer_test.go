@@ -0,0 +1,148 @@
+package cart
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCart_OptimizePromotions(t *testing.T) {
+	tests := []struct {
+		name        string
+		quantity    int64
+		promotions  []Promotion
+		wantTotal   decimal.Decimal
+		wantReasons map[string]DecisionReason
+	}{
+		{
+			name:     "buy 1 get 1 beats percentage",
+			quantity: 4,
+			promotions: []Promotion{
+				{ID: "P1", ProductID: "C", PromotionType: PercentageDiscount, Discount: 20},
+				{ID: "P2", ProductID: "C", PromotionType: Buy1Get1Free},
+			},
+			wantTotal: decimal.NewFromFloat(200.00), // 2 of 4 paid
+			wantReasons: map[string]DecisionReason{
+				"P1": ReasonExcluded,
+				"P2": ReasonCheapest,
+			},
+		},
+		{
+			name:     "percentage beats buy 1 get 1 on a single unit",
+			quantity: 1,
+			promotions: []Promotion{
+				{ID: "P1", ProductID: "C", PromotionType: Buy1Get1Free},
+				{ID: "P2", ProductID: "C", PromotionType: PercentageDiscount, Discount: 20},
+			},
+			wantTotal: decimal.NewFromFloat(80.00),
+			wantReasons: map[string]DecisionReason{
+				"P1": ReasonNoSaving,
+				"P2": ReasonCheapest,
+			},
+		},
+		{
+			name:     "stacked promotions beat an exclusive one",
+			quantity: 2,
+			promotions: []Promotion{
+				{ID: "P1", ProductID: "C", PromotionType: PercentageDiscount, Discount: 30},
+				{ID: "P2", ProductID: "C", PromotionType: PercentageDiscount, Discount: 20, Stacking: Stackable},
+				{ID: "P3", ProductID: "C", PromotionType: PercentageDiscount, Discount: 20, Stacking: Stackable},
+			},
+			wantTotal: decimal.NewFromFloat(128.00), // 200 * 0.8 * 0.8
+			wantReasons: map[string]DecisionReason{
+				"P1": ReasonExclusiveConflict,
+				"P2": ReasonCheapest,
+				"P3": ReasonCheapest,
+			},
+		},
+		{
+			name:     "zero discount is left out",
+			quantity: 2,
+			promotions: []Promotion{
+				{ID: "P1", ProductID: "C", PromotionType: PercentageDiscount, Discount: 0, Stacking: Stackable},
+				{ID: "P2", ProductID: "C", PromotionType: PercentageDiscount, Discount: 10, Stacking: Stackable},
+			},
+			wantTotal: decimal.NewFromFloat(180.00),
+			wantReasons: map[string]DecisionReason{
+				"P1": ReasonNoSaving,
+				"P2": ReasonCheapest,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart()
+			cart.Strategy = BestDeal
+			err := cart.AddProduct(Product{ID: "C", Price: decimal.NewFromFloat(100.00)}, tt.quantity)
+			require.NoError(t, err)
+			for _, promo := range tt.promotions {
+				cart.AddPromotion(promo)
+			}
+
+			decisions := cart.OptimizePromotions()
+			require.Len(t, decisions, 2)
+			assert.Equal(t, "C", decisions[0].ProductID)
+			assert.True(t, tt.wantTotal.Equal(decisions[0].Total), "Expected %s, got %s", tt.wantTotal, decisions[0].Total)
+
+			gotReasons := make(map[string]DecisionReason)
+			for _, decision := range decisions[0].Decisions {
+				gotReasons[decision.PromotionID] = decision.Reason
+				assert.Equal(t, decision.Reason == ReasonCheapest, decision.Chosen)
+			}
+			assert.Equal(t, tt.wantReasons, gotReasons)
+
+			got := cart.CalculateTotal()
+			assert.True(t, tt.wantTotal.Equal(got), "Expected %s, got %s", tt.wantTotal, got)
+		})
+	}
+}
+
+func TestCart_CalculateTotal_Strategy(t *testing.T) {
+	setup := func(strategy PromotionStrategy) *Cart {
+		cart := NewCart()
+		cart.Strategy = strategy
+		err := cart.AddProduct(Product{ID: "C", Price: decimal.NewFromFloat(100.00)}, 4)
+		require.NoError(t, err)
+		cart.AddPromotion(Promotion{ID: "P1", ProductID: "C", PromotionType: PercentageDiscount, Discount: 10})
+		cart.AddPromotion(Promotion{ID: "P2", ProductID: "C", PromotionType: Buy1Get1Free})
+		cart.AddPromotion(Promotion{ID: "T1", PromotionType: TotalDiscount, Discount: 0})
+		return cart
+	}
+
+	// First come first served applies the 10% discount
+	got := setup(PriorityOrder).CalculateTotal()
+	assert.True(t, decimal.NewFromFloat(360.00).Equal(got), "got %s", got)
+
+	breakdown := setup(BestDeal).Breakdown()
+	assert.True(t, decimal.NewFromFloat(200.00).Equal(breakdown.Total), "got %s", breakdown.Total)
+	require.Len(t, breakdown.Lines[0].Promotions, 1)
+	assert.Equal(t, "P2", breakdown.Lines[0].Promotions[0].PromotionID)
+	assert.Empty(t, breakdown.CartPromotions)
+}
+
+func TestCart_OptimizePromotions_Rounding(t *testing.T) {
+	// The line rounds to 100 JPY, short of the minimum spend
+	cart := NewCart()
+	cart.Strategy = BestDeal
+	require.NoError(t, cart.AddProduct(Product{ID: "C", Price: decimal.RequireFromString("100.4"), Currency: "JPY"}, 1))
+	cart.AddPromotion(Promotion{ID: "T1", PromotionType: FixedCartDiscount, Amount: decimal.NewFromInt(10), MinSpend: decimal.RequireFromString("100.4")})
+
+	decisions := cart.OptimizePromotions()
+	require.Len(t, decisions, 2)
+	assert.True(t, decimal.NewFromInt(100).Equal(decisions[0].Total), "got %s", decisions[0].Total)
+
+	scope := decisions[1]
+	assert.True(t, decimal.NewFromInt(100).Equal(scope.Before), "got %s", scope.Before)
+	assert.True(t, decimal.NewFromInt(100).Equal(scope.Total), "got %s", scope.Total)
+	require.Len(t, scope.Decisions, 1)
+	assert.False(t, scope.Decisions[0].Chosen)
+	assert.Equal(t, ReasonNoSaving, scope.Decisions[0].Reason)
+
+	breakdown := cart.Breakdown()
+	assert.True(t, breakdown.Subtotal.Equal(scope.Before), "got %s", breakdown.Subtotal)
+	assert.True(t, breakdown.Total.Equal(scope.Total), "got %s", breakdown.Total)
+	assert.Empty(t, breakdown.CartPromotions)
+}
@@ -1,27 +1,132 @@
 package cart
 
-import "github.com/shopspring/decimal"
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// TierMode selects how quantity tiers price a line
+type TierMode string
+
+const (
+	// TierAllUnits prices every unit at the highest tier reached. It is the zero value.
+	TierAllUnits TierMode = "allUnits"
+	// TierGraduated prices each unit at the tier its position falls into.
+	TierGraduated TierMode = "graduated"
+)
 
 type (
 	Product struct {
 		ID          string
 		Description string
 		Price       decimal.Decimal // as decimal price
+		Currency    string          // ISO 4217 code of Price, empty means the cart's currency
 		Discount    int64           // percentage discount (0-100)
+		PriceTiers  []PriceTier     // quantity breakpoints, below the first one Price is used
+		TierMode    TierMode
+		TaxCategory TaxCategory // empty means TaxStandard
+
+		// Unit is what Price is charged per. Measured products, such as coffee by the
+		// kg, are added in decimal amounts with Cart.AddMeasure.
+		Unit       UnitOfMeasure
+		MinMeasure decimal.Decimal // smallest amount per line, zero means no minimum
+		Step       decimal.Decimal // amounts must be multiples of it, zero means DefaultMeasureStep
+
+		// MaxPerCustomer caps the units of a limited-edition product one customer
+		// may buy over all its variants, 0 means no limit
+		MaxPerCustomer int64
+
+		// Variants are the versions sold under their own SKU. A product with variants
+		// is added to a cart through Variant, which sets SKU and Options.
+		Variants []Variant
+		SKU      string            // stock keeping unit of a cart line, empty means ID
+		Options  map[string]string // options of the chosen variant
+
+		// Shipping measures of one unit, zero means unknown
+		Weight decimal.Decimal // kg
+		Length decimal.Decimal // cm
+		Width  decimal.Decimal // cm
+		Height decimal.Decimal // cm
+	}
+
+	// PriceTier sets the unit price from MinQuantity units upwards
+	PriceTier struct {
+		MinQuantity int64
+		UnitPrice   decimal.Decimal
 	}
 )
 
 // GetDiscountedPrice calculates the final price after applying the product discount
 func (p Product) GetDiscountedPrice() decimal.Decimal {
-	if p.Discount <= 0 || p.Discount > 100 {
-		return p.Price
+	return p.applyDiscount(p.Price)
+}
+
+// GetDiscountedLinePrice prices qty units with tiers and then applies the product discount
+func (p Product) GetDiscountedLinePrice(qty int64) decimal.Decimal {
+	return p.applyDiscount(p.TieredPrice(qty))
+}
+
+// UnitPriceAt returns the unit price of the highest tier reached by qty
+func (p Product) UnitPriceAt(qty int64) decimal.Decimal {
+	price := p.Price
+	for _, tier := range p.sortedTiers() {
+		if qty < tier.MinQuantity {
+			break
+		}
+		price = tier.UnitPrice
 	}
-	discount := decimal.NewFromInt(p.Discount)
-	hundred := decimal.NewFromInt(100)
-	return p.Price.Mul(hundred.Sub(discount)).Div(hundred)
+	return price
+}
+
+// TieredPrice prices qty units according to the tiers and tier mode, before the product discount
+func (p Product) TieredPrice(qty int64) decimal.Decimal {
+	if qty <= 0 {
+		return decimal.Zero
+	}
+	if p.TierMode != TierGraduated {
+		return p.UnitPriceAt(qty).Mul(decimal.NewFromInt(qty))
+	}
+
+	total := decimal.Zero
+	price := p.Price
+	from := int64(1)
+	for _, tier := range p.sortedTiers() {
+		if qty < tier.MinQuantity {
+			break
+		}
+		// Units from..MinQuantity-1 belong to the previous tier
+		total = total.Add(price.Mul(decimal.NewFromInt(tier.MinQuantity - from)))
+		price = tier.UnitPrice
+		from = tier.MinQuantity
+	}
+	return total.Add(price.Mul(decimal.NewFromInt(qty - from + 1)))
+}
+
+// Volume returns the volume of one unit in cm³
+func (p Product) Volume() decimal.Decimal {
+	return p.Length.Mul(p.Width).Mul(p.Height)
 }
 
 // ValidateDiscount checks if the discount percentage is valid
 func (p Product) ValidateDiscount() bool {
 	return p.Discount >= 0 && p.Discount <= 100
 }
+
+func (p Product) applyDiscount(price decimal.Decimal) decimal.Decimal {
+	if p.Discount <= 0 || p.Discount > 100 {
+		return price
+	}
+	discount := decimal.NewFromInt(p.Discount)
+	hundred := decimal.NewFromInt(100)
+	return price.Mul(hundred.Sub(discount)).Div(hundred)
+}
+
+func (p Product) sortedTiers() []PriceTier {
+	tiers := make([]PriceTier, len(p.PriceTiers))
+	copy(tiers, p.PriceTiers)
+	sort.SliceStable(tiers, func(i, j int) bool {
+		return tiers[i].MinQuantity < tiers[j].MinQuantity
+	})
+	return tiers
+}
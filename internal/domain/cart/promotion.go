@@ -1,7 +1,10 @@
 package cart
 
 import (
-	"math"
+	"errors"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -12,6 +15,21 @@ const (
 	PercentageDiscount PromotionType = "percentageDiscount"
 	Buy1Get1Free       PromotionType = "buy1Get1Free"
 	TotalDiscount      PromotionType = "totalDiscount"
+	BuyXGetY           PromotionType = "buyXGetY"
+	FixedAmountOff     PromotionType = "fixedAmountOff"    // Amount off each unit of a product
+	FixedCartDiscount  PromotionType = "fixedCartDiscount" // Amount off the cart total
+	BundlePrice        PromotionType = "bundlePrice"       // BundleItems sold together for Amount
+)
+
+// StackingRule declares how a promotion combines with others in the same scope.
+// A product's promotions form one scope and the cart-wide promotions form another.
+type StackingRule string
+
+const (
+	// Exclusive promotions apply alone. The zero value is treated as Exclusive.
+	Exclusive StackingRule = "exclusive"
+	// Stackable promotions apply one after another on the running price.
+	Stackable StackingRule = "stackable"
 )
 
 type (
@@ -19,22 +37,266 @@ type (
 		ID            string
 		Discount      int64
 		ProductID     string
+		SKU           string // limits a product promotion to one variant, empty means every variant
 		PromotionType PromotionType
+		Stacking      StackingRule
+		Priority      int64 // lower values are evaluated first
+
+		// Money amounts, zero means unset
+		Amount      decimal.Decimal // amount off for the fixed amount types
+		MinSpend    decimal.Decimal // the scope total must reach this before the promotion applies
+		MaxDiscount decimal.Decimal // caps the saving, e.g. 20% off up to 500
+
+		// BuyXGetY settings. Discount is the percentage taken off each reward unit.
+		BuyQuantity     int64  // X, units that must be bought
+		GetQuantity     int64  // Y, units discounted per application
+		MaxApplications int64  // 0 means unlimited
+		BuyProductID    string // qualifying product when it differs from ProductID
+
+		// BundlePrice components, MaxApplications caps the number of bundles
+		BundleItems []BundleItem
+
+		// Active period, zero means open-ended. EndsAt is exclusive.
+		StartsAt time.Time
+		EndsAt   time.Time
+		Schedule *Schedule // recurring windows inside the active period, nil means always
+	}
+
+	// lineContext carries what a promotion may need besides the running line total
+	lineContext struct {
+		quantity   int64
+		measure    decimal.Decimal  // amount of a measured line, zero for products sold by the unit
+		quantities map[string]int64 // product ID -> quantity in the cart, over all variants
+		at         time.Time        // pricing instant, promotions inactive at it are skipped
 	}
 )
 
 func (p *Promotion) CalculatePrice(price decimal.Decimal, qty int64) decimal.Decimal {
+	line := lineContext{quantity: qty}
+	return p.applyToLine(price.Mul(decimal.NewFromInt(qty)), line)
+}
+
+// IsExclusive reports whether the promotion must be applied alone
+func (p *Promotion) IsExclusive() bool {
+	return p.Stacking != Stackable
+}
+
+// IsCartWide reports whether the promotion applies to the cart total instead of a product
+func (p *Promotion) IsCartWide() bool {
+	return p.PromotionType == TotalDiscount || p.PromotionType == FixedCartDiscount
+}
+
+// applyToLine applies the promotion to the running total of a line or of the cart.
+// The minimum spend is checked against that running total, the saving is capped by
+// MaxDiscount and the result never goes below zero.
+func (p *Promotion) applyToLine(lineTotal decimal.Decimal, line lineContext) decimal.Decimal {
+	if p.MinSpend.IsPositive() && lineTotal.LessThan(p.MinSpend) {
+		return lineTotal
+	}
+
+	next := p.calculate(lineTotal, line)
+	if p.MaxDiscount.IsPositive() && lineTotal.Sub(next).GreaterThan(p.MaxDiscount) {
+		next = lineTotal.Sub(p.MaxDiscount)
+	}
+	if next.IsNegative() {
+		return decimal.Zero
+	}
+	return next
+}
+
+func (p *Promotion) calculate(lineTotal decimal.Decimal, line lineContext) decimal.Decimal {
 	switch p.PromotionType {
-	case PercentageDiscount:
+	case PercentageDiscount, TotalDiscount:
 		discount := decimal.NewFromInt(p.Discount)
 		hundred := decimal.NewFromInt(100)
-		quantity := decimal.NewFromInt(qty)
-		discountedPrice := price.Mul(hundred.Sub(discount)).Div(hundred)
-		return discountedPrice.Mul(quantity)
+		return lineTotal.Mul(hundred.Sub(discount)).Div(hundred)
 	case Buy1Get1Free:
-		paidQty := math.Ceil(float64(qty) / 2) // 3 / 2 = 1.5 := 2
-		paidQtyDecimal := decimal.NewFromFloat(paidQty)
-		return price.Mul(paidQtyDecimal)
+		bogo := Promotion{BuyQuantity: 1, GetQuantity: 1, Discount: 100}
+		return lineTotal.Sub(bogo.buyXGetYSaving(lineTotal, line))
+	case BuyXGetY:
+		return lineTotal.Sub(p.buyXGetYSaving(lineTotal, line))
+	case FixedAmountOff:
+		return lineTotal.Sub(p.Amount.Mul(line.units()))
+	case FixedCartDiscount:
+		return lineTotal.Sub(p.Amount)
 	}
 	return decimal.Zero
 }
+
+// units returns the units of the line, or its amount when it is measured
+func (l lineContext) units() decimal.Decimal {
+	if l.measure.IsPositive() {
+		return l.measure
+	}
+	return decimal.NewFromInt(l.quantity)
+}
+
+// appliesTo reports whether the promotion can price the line at its pricing instant.
+// Promotions that count units skip measured lines.
+func (p *Promotion) appliesTo(line lineContext) bool {
+	if line.measure.IsPositive() && p.NeedsWholeUnits() {
+		return false
+	}
+	return p.IsActiveAt(line.at)
+}
+
+// rewardUnits returns how many units of the line get the BuyXGetY discount
+func (p *Promotion) rewardUnits(line lineContext) int64 {
+	if p.BuyQuantity <= 0 || p.GetQuantity <= 0 || line.quantity <= 0 {
+		return 0
+	}
+
+	var applications int64
+	crossProduct := p.BuyProductID != "" && p.BuyProductID != p.ProductID
+	if crossProduct {
+		applications = line.quantities[p.BuyProductID] / p.BuyQuantity
+	} else {
+		// Bought and reward units come from the same line, e.g. buy 2 get 1 needs 3 units
+		applications = line.quantity / (p.BuyQuantity + p.GetQuantity)
+	}
+	if p.MaxApplications > 0 && applications > p.MaxApplications {
+		applications = p.MaxApplications
+	}
+
+	units := applications * p.GetQuantity
+	if units > line.quantity {
+		units = line.quantity
+	}
+	return units
+}
+
+// buyXGetYSaving is the share of the line total taken off the reward units.
+// Multiplying before the single division keeps the result exact for whole unit prices.
+func (p *Promotion) buyXGetYSaving(lineTotal decimal.Decimal, line lineContext) decimal.Decimal {
+	units := p.rewardUnits(line)
+	if units == 0 {
+		return decimal.Zero
+	}
+
+	numerator := lineTotal.Mul(decimal.NewFromInt(units)).Mul(decimal.NewFromInt(p.Discount))
+	return numerator.Div(decimal.NewFromInt(line.quantity * 100))
+}
+
+// sortPromotions orders promotions by priority, keeping insertion order for ties
+func sortPromotions(promotions []*Promotion) []*Promotion {
+	sorted := make([]*Promotion, len(promotions))
+	copy(sorted, promotions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+	return sorted
+}
+
+// applyPromotions evaluates the promotions of one scope in priority order.
+// An exclusive promotion is applied only when it comes first and then ends the
+// evaluation; once a stackable promotion has been applied, exclusive ones are skipped.
+// Promotions that are not active at the pricing instant, that count units of a
+// measured line, or that save nothing, such as one whose MinSpend is not met, are
+// ignored and do not end the evaluation.
+func applyPromotions(promotions []*Promotion, lineTotal decimal.Decimal, line lineContext) (decimal.Decimal, []PromotionSaving) {
+	var savings []PromotionSaving
+	for _, promo := range sortPromotions(promotions) {
+		if !promo.appliesTo(line) {
+			continue
+		}
+		if promo.IsExclusive() && len(savings) > 0 {
+			continue
+		}
+
+		next := promo.applyToLine(lineTotal, line)
+		if !next.LessThan(lineTotal) {
+			continue
+		}
+		savings = append(savings, PromotionSaving{
+			PromotionID:   promo.ID,
+			PromotionType: promo.PromotionType,
+			Amount:        lineTotal.Sub(next),
+		})
+		lineTotal = next
+
+		if promo.IsExclusive() {
+			break
+		}
+	}
+
+	return lineTotal, savings
+}
+
+// ValidatePromotion validates promotion data
+func ValidatePromotion(promotion Promotion) error {
+	switch promotion.PromotionType {
+	case PercentageDiscount, Buy1Get1Free, TotalDiscount:
+	case FixedAmountOff, FixedCartDiscount:
+		if !promotion.Amount.IsPositive() {
+			return errors.New("promotion amount must be positive")
+		}
+	case BundlePrice:
+		if err := validateBundle(promotion); err != nil {
+			return err
+		}
+	case BuyXGetY:
+		if promotion.BuyQuantity <= 0 || promotion.GetQuantity <= 0 {
+			return errors.New("buy and get quantities must be positive")
+		}
+		if promotion.Discount <= 0 {
+			return errors.New("buy X get Y discount must be between 1 and 100")
+		}
+		if promotion.MaxApplications < 0 {
+			return errors.New("max applications cannot be negative")
+		}
+	default:
+		return errors.New("unknown promotion type")
+	}
+
+	if !promotion.IsCartWide() && promotion.PromotionType != BundlePrice && strings.TrimSpace(promotion.ProductID) == "" {
+		return errors.New("promotion product ID cannot be empty")
+	}
+
+	if promotion.SKU != "" && (promotion.IsCartWide() || promotion.PromotionType == BundlePrice) {
+		return errors.New("only product promotions can target a variant")
+	}
+
+	if promotion.Amount.IsNegative() || promotion.MinSpend.IsNegative() || promotion.MaxDiscount.IsNegative() {
+		return errors.New("promotion amounts cannot be negative")
+	}
+
+	if promotion.Discount < 0 || promotion.Discount > 100 {
+		return errors.New("promotion discount must be between 0 and 100")
+	}
+
+	switch promotion.Stacking {
+	case "", Exclusive, Stackable:
+	default:
+		return errors.New("unknown promotion stacking rule")
+	}
+
+	return validateSchedule(promotion)
+}
+
+func validateBundle(promotion Promotion) error {
+	if promotion.Amount.IsNegative() {
+		return errors.New("bundle price cannot be negative")
+	}
+	if len(promotion.BundleItems) == 0 {
+		return errors.New("bundle must have at least one item")
+	}
+	if promotion.MaxApplications < 0 {
+		return errors.New("max applications cannot be negative")
+	}
+
+	seen := make(map[string]bool, len(promotion.BundleItems))
+	for _, item := range promotion.BundleItems {
+		if strings.TrimSpace(item.ProductID) == "" {
+			return errors.New("bundle item product ID cannot be empty")
+		}
+		if item.Quantity <= 0 {
+			return errors.New("bundle item quantity must be positive")
+		}
+		if seen[item.ProductID] {
+			return errors.New("bundle items must be distinct products")
+		}
+		seen[item.ProductID] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package cart
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromotion_CalculatePrice_BuyXGetY(t *testing.T) {
+	tests := []struct {
+		name      string
+		promotion Promotion
+		price     decimal.Decimal
+		qty       int64
+		want      decimal.Decimal
+	}{
+		{
+			name:      "buy 2 get 1 free",
+			promotion: Promotion{PromotionType: BuyXGetY, BuyQuantity: 2, GetQuantity: 1, Discount: 100},
+			price:     decimal.NewFromFloat(30.00),
+			qty:       7,
+			want:      decimal.NewFromFloat(150.00), // 2 free of 7
+		},
+		{
+			name:      "buy 3 get 2 at 50% off",
+			promotion: Promotion{PromotionType: BuyXGetY, BuyQuantity: 3, GetQuantity: 2, Discount: 50},
+			price:     decimal.NewFromFloat(10.00),
+			qty:       10,
+			want:      decimal.NewFromFloat(80.00), // 4 units at half price
+		},
+		{
+			name:      "max applications",
+			promotion: Promotion{PromotionType: BuyXGetY, BuyQuantity: 1, GetQuantity: 1, Discount: 100, MaxApplications: 1},
+			price:     decimal.NewFromFloat(10.00),
+			qty:       6,
+			want:      decimal.NewFromFloat(50.00),
+		},
+		{
+			name:      "not enough units",
+			promotion: Promotion{PromotionType: BuyXGetY, BuyQuantity: 2, GetQuantity: 1, Discount: 100},
+			price:     decimal.NewFromFloat(10.00),
+			qty:       2,
+			want:      decimal.NewFromFloat(20.00),
+		},
+		{
+			name:      "buy 1 get 1 free odd quantity",
+			promotion: Promotion{PromotionType: Buy1Get1Free},
+			price:     decimal.RequireFromString("33.33"),
+			qty:       3,
+			want:      decimal.RequireFromString("66.66"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.promotion.CalculatePrice(tt.price, tt.qty)
+			assert.True(t, tt.want.Equal(got), "Expected %s, got %s", tt.want.String(), got.String())
+		})
+	}
+}
+
+func TestCart_Breakdown_BuyXGetYWithoutReward(t *testing.T) {
+	cart := NewCart()
+	require.NoError(t, cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(100.00)}, 1))
+	// Exclusive, but one unit earns no reward under buy 2 get 1
+	cart.AddPromotion(Promotion{ID: "b2g1", ProductID: "A", PromotionType: BuyXGetY, BuyQuantity: 2, GetQuantity: 1, Discount: 100})
+	cart.AddPromotion(Promotion{ID: "a10", ProductID: "A", PromotionType: PercentageDiscount, Discount: 10})
+
+	got := cart.Breakdown()
+
+	require.Len(t, got.Lines, 1)
+	require.Len(t, got.Lines[0].Promotions, 1)
+	assert.Equal(t, "a10", got.Lines[0].Promotions[0].PromotionID)
+	assert.True(t, decimal.NewFromFloat(90.00).Equal(got.Total), "got %s", got.Total)
+}
+
+func TestCart_CalculateTotal_CrossProductBuyXGetY(t *testing.T) {
+	tests := []struct {
+		name string
+		qtyA int64
+		qtyB int64
+		max  int64
+		want decimal.Decimal
+	}{
+		{
+			name: "buy A get B free",
+			qtyA: 2,
+			qtyB: 2,
+			want: decimal.NewFromFloat(200.00), // A only
+		},
+		{
+			name: "more B than earned",
+			qtyA: 1,
+			qtyB: 3,
+			want: decimal.NewFromFloat(140.00), // 100 + 2 * 20
+		},
+		{
+			name: "capped applications",
+			qtyA: 3,
+			qtyB: 3,
+			max:  1,
+			want: decimal.NewFromFloat(340.00), // 300 + 2 * 20
+		},
+		{
+			name: "no qualifying product",
+			qtyB: 2,
+			want: decimal.NewFromFloat(40.00),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart()
+			if tt.qtyA > 0 {
+				err := cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(100.00)}, tt.qtyA)
+				require.NoError(t, err)
+			}
+			err := cart.AddProduct(Product{ID: "B", Price: decimal.NewFromFloat(20.00)}, tt.qtyB)
+			require.NoError(t, err)
+			cart.AddPromotion(Promotion{
+				ProductID:       "B",
+				PromotionType:   BuyXGetY,
+				BuyProductID:    "A",
+				BuyQuantity:     1,
+				GetQuantity:     1,
+				Discount:        100,
+				MaxApplications: tt.max,
+			})
+
+			got := cart.CalculateTotal()
+			assert.True(t, tt.want.Equal(got), "Expected %s, got %s", tt.want.String(), got.String())
+		})
+	}
+}
+
+func TestValidatePromotion(t *testing.T) {
+	tests := []struct {
+		name      string
+		promotion Promotion
+		errorMsg  string
+	}{
+		{
+			name:      "valid buy X get Y",
+			promotion: Promotion{ProductID: "A", PromotionType: BuyXGetY, BuyQuantity: 2, GetQuantity: 1, Discount: 100},
+		},
+		{
+			name:      "buy X get Y without quantities",
+			promotion: Promotion{ProductID: "A", PromotionType: BuyXGetY, Discount: 100},
+			errorMsg:  "buy and get quantities must be positive",
+		},
+		{
+			name:      "buy X get Y without discount",
+			promotion: Promotion{ProductID: "A", PromotionType: BuyXGetY, BuyQuantity: 2, GetQuantity: 1},
+			errorMsg:  "buy X get Y discount must be between 1 and 100",
+		},
+		{
+			name:      "fixed amount without amount",
+			promotion: Promotion{ProductID: "A", PromotionType: FixedAmountOff},
+			errorMsg:  "promotion amount must be positive",
+		},
+		{
+			name:      "fixed cart discount without product",
+			promotion: Promotion{PromotionType: FixedCartDiscount, Amount: decimal.NewFromFloat(100.00)},
+		},
+		{
+			name:      "negative cap",
+			promotion: Promotion{PromotionType: TotalDiscount, Discount: 10, MaxDiscount: decimal.NewFromFloat(-1.00)},
+			errorMsg:  "promotion amounts cannot be negative",
+		},
+		{
+			name:      "unknown type",
+			promotion: Promotion{ProductID: "A", PromotionType: "unknown"},
+			errorMsg:  "unknown promotion type",
+		},
+		{
+			name:      "missing product",
+			promotion: Promotion{PromotionType: PercentageDiscount, Discount: 10},
+			errorMsg:  "promotion product ID cannot be empty",
+		},
+		{
+			name:      "unknown stacking rule",
+			promotion: Promotion{ProductID: "A", PromotionType: PercentageDiscount, Stacking: "sometimes"},
+			errorMsg:  "unknown promotion stacking rule",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePromotion(tt.promotion)
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.errorMsg, err.Error())
+		})
+	}
+}
+
+func TestCart_CalculateTotal_AmountPromotions(t *testing.T) {
+	tests := []struct {
+		name       string
+		promotions []Promotion
+		want       decimal.Decimal
+	}{
+		{
+			name: "fixed amount off per item",
+			promotions: []Promotion{
+				{ProductID: "A", PromotionType: FixedAmountOff, Amount: decimal.NewFromFloat(15.00)},
+			},
+			want: decimal.NewFromFloat(1040.00), // (400 - 4 * 15) + 700
+		},
+		{
+			name: "fixed amount off never goes below zero",
+			promotions: []Promotion{
+				{ProductID: "A", PromotionType: FixedAmountOff, Amount: decimal.NewFromFloat(150.00)},
+			},
+			want: decimal.NewFromFloat(700.00),
+		},
+		{
+			name: "fixed amount off the cart",
+			promotions: []Promotion{
+				{PromotionType: FixedCartDiscount, Amount: decimal.NewFromFloat(100.00)},
+			},
+			want: decimal.NewFromFloat(1000.00),
+		},
+		{
+			name: "spend 1000 get 100 off",
+			promotions: []Promotion{
+				{PromotionType: FixedCartDiscount, Amount: decimal.NewFromFloat(100.00), MinSpend: decimal.NewFromFloat(1000.00)},
+			},
+			want: decimal.NewFromFloat(1000.00),
+		},
+		{
+			name: "minimum spend not reached",
+			promotions: []Promotion{
+				{PromotionType: FixedCartDiscount, Amount: decimal.NewFromFloat(100.00), MinSpend: decimal.NewFromFloat(1500.00)},
+			},
+			want: decimal.NewFromFloat(1100.00),
+		},
+		{
+			name: "minimum spend checked after earlier promotions",
+			promotions: []Promotion{
+				{PromotionType: TotalDiscount, Discount: 10, Stacking: Stackable},
+				{PromotionType: FixedCartDiscount, Amount: decimal.NewFromFloat(100.00), MinSpend: decimal.NewFromFloat(1000.00), Stacking: Stackable, Priority: 1},
+			},
+			want: decimal.NewFromFloat(990.00), // 1100 * 0.9, 990 is below 1000
+		},
+		{
+			name: "percentage off with cap",
+			promotions: []Promotion{
+				{PromotionType: TotalDiscount, Discount: 50, MaxDiscount: decimal.NewFromFloat(500.00)},
+			},
+			want: decimal.NewFromFloat(600.00),
+		},
+		{
+			name: "percentage off below cap",
+			promotions: []Promotion{
+				{PromotionType: TotalDiscount, Discount: 20, MaxDiscount: decimal.NewFromFloat(500.00)},
+			},
+			want: decimal.NewFromFloat(880.00),
+		},
+		{
+			name: "cart discount larger than total",
+			promotions: []Promotion{
+				{PromotionType: FixedCartDiscount, Amount: decimal.NewFromFloat(5000.00)},
+			},
+			want: decimal.Zero,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart()
+			err := cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(100.00)}, 4)
+			require.NoError(t, err)
+			err = cart.AddProduct(Product{ID: "B", Price: decimal.NewFromFloat(700.00)}, 1)
+			require.NoError(t, err)
+			for _, promo := range tt.promotions {
+				require.NoError(t, ValidatePromotion(promo))
+				cart.AddPromotion(promo)
+			}
+
+			got := cart.CalculateTotal()
+			assert.True(t, tt.want.Equal(got), "Expected %s, got %s", tt.want.String(), got.String())
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package cart
+
+import (
+	"errors"
+	"time"
+)
+
+// Clock returns the instant a cart is priced at. Tests can replace it to price a
+// cart as of any instant.
+type Clock func() time.Time
+
+// Schedule limits a promotion to recurring windows, e.g. weekday happy hours
+type Schedule struct {
+	Weekdays  []time.Weekday // empty means every day
+	StartHour int            // first hour of the window, 0-23
+	EndHour   int            // hour the window closes, 0-24. Equal to StartHour means all day.
+	Location  string         // IANA time zone name such as "Asia/Bangkok", empty means UTC
+}
+
+// Contains reports whether t falls in a window of the schedule.
+// A window whose EndHour is before its StartHour runs past midnight and belongs
+// to the weekday it starts on.
+func (s Schedule) Contains(t time.Time) bool {
+	loc, err := s.location()
+	if err != nil {
+		return false
+	}
+	local := t.In(loc)
+	hour := local.Hour()
+	day := local.Weekday()
+
+	switch {
+	case s.StartHour == s.EndHour:
+	case s.StartHour < s.EndHour:
+		if hour < s.StartHour || hour >= s.EndHour {
+			return false
+		}
+	case hour >= s.StartHour:
+	case hour < s.EndHour:
+		// After midnight the window started the day before
+		day = local.AddDate(0, 0, -1).Weekday()
+	default:
+		return false
+	}
+
+	if len(s.Weekdays) == 0 {
+		return true
+	}
+	for _, weekday := range s.Weekdays {
+		if weekday == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Schedule) location() (*time.Location, error) {
+	if s.Location == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(s.Location)
+}
+
+// IsActiveAt reports whether the promotion applies at the given instant
+func (p *Promotion) IsActiveAt(t time.Time) bool {
+	if !p.StartsAt.IsZero() && t.Before(p.StartsAt) {
+		return false
+	}
+	if !p.EndsAt.IsZero() && !t.Before(p.EndsAt) {
+		return false
+	}
+	if p.Schedule != nil {
+		return p.Schedule.Contains(t)
+	}
+	return true
+}
+
+// now returns the pricing instant from the cart's clock, defaulting to time.Now
+func (c *Cart) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+	return c.Clock()
+}
+
+// activePromotions keeps the promotions that apply at the given instant
+func activePromotions(promotions []*Promotion, at time.Time) []*Promotion {
+	active := make([]*Promotion, 0, len(promotions))
+	for _, promo := range promotions {
+		if promo.IsActiveAt(at) {
+			active = append(active, promo)
+		}
+	}
+	return active
+}
+
+func validateSchedule(promotion Promotion) error {
+	if !promotion.StartsAt.IsZero() && !promotion.EndsAt.IsZero() && !promotion.EndsAt.After(promotion.StartsAt) {
+		return errors.New("promotion must end after it starts")
+	}
+
+	schedule := promotion.Schedule
+	if schedule == nil {
+		return nil
+	}
+	if schedule.StartHour < 0 || schedule.StartHour > 23 || schedule.EndHour < 0 || schedule.EndHour > 24 {
+		return errors.New("schedule hours must be between 0 and 24")
+	}
+	for _, weekday := range schedule.Weekdays {
+		if weekday < time.Sunday || weekday > time.Saturday {
+			return errors.New("unknown schedule weekday")
+		}
+	}
+	if _, err := schedule.location(); err != nil {
+		return errors.New("unknown schedule time zone")
+	}
+
+	return nil
+}
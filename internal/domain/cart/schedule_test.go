@@ -0,0 +1,214 @@
+package cart
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Contains(t *testing.T) {
+	bangkok, err := time.LoadLocation("Asia/Bangkok")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		at       time.Time
+		want     bool
+	}{
+		{
+			name:     "all day every day",
+			schedule: Schedule{},
+			at:       time.Date(2025, 6, 2, 3, 0, 0, 0, time.UTC),
+			want:     true,
+		},
+		{
+			name:     "inside happy hour",
+			schedule: Schedule{StartHour: 17, EndHour: 19},
+			at:       time.Date(2025, 6, 2, 18, 59, 0, 0, time.UTC),
+			want:     true,
+		},
+		{
+			name:     "end hour is exclusive",
+			schedule: Schedule{StartHour: 17, EndHour: 19},
+			at:       time.Date(2025, 6, 2, 19, 0, 0, 0, time.UTC),
+			want:     false,
+		},
+		{
+			name:     "weekday only",
+			schedule: Schedule{Weekdays: []time.Weekday{time.Saturday, time.Sunday}},
+			at:       time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC), // Monday
+			want:     false,
+		},
+		{
+			name:     "hours in the schedule time zone",
+			schedule: Schedule{StartHour: 17, EndHour: 19, Location: "Asia/Bangkok"},
+			at:       time.Date(2025, 6, 2, 10, 30, 0, 0, time.UTC), // 17:30 in Bangkok
+			want:     true,
+		},
+		{
+			name:     "weekday in the schedule time zone",
+			schedule: Schedule{Weekdays: []time.Weekday{time.Tuesday}, Location: "Asia/Bangkok"},
+			at:       time.Date(2025, 6, 3, 1, 0, 0, 0, bangkok), // Monday in UTC
+			want:     true,
+		},
+		{
+			name:     "window past midnight belongs to the day it starts",
+			schedule: Schedule{Weekdays: []time.Weekday{time.Friday}, StartHour: 22, EndHour: 2},
+			at:       time.Date(2025, 6, 7, 1, 0, 0, 0, time.UTC), // Saturday 01:00
+			want:     true,
+		},
+		{
+			name:     "outside window past midnight",
+			schedule: Schedule{StartHour: 22, EndHour: 2},
+			at:       time.Date(2025, 6, 7, 12, 0, 0, 0, time.UTC),
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.schedule.Contains(tt.at))
+		})
+	}
+}
+
+func TestCart_CalculateTotalAt_ScheduledPromotions(t *testing.T) {
+	saleStart := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	saleEnd := saleStart.Add(24 * time.Hour)
+
+	tests := []struct {
+		name       string
+		promotions []Promotion
+		at         time.Time
+		want       decimal.Decimal
+	}{
+		{
+			name:       "flash sale running",
+			promotions: []Promotion{{ProductID: "A", PromotionType: PercentageDiscount, Discount: 50, StartsAt: saleStart, EndsAt: saleEnd}},
+			at:         saleStart.Add(time.Hour),
+			want:       decimal.NewFromFloat(400.00),
+		},
+		{
+			name:       "flash sale not started",
+			promotions: []Promotion{{ProductID: "A", PromotionType: PercentageDiscount, Discount: 50, StartsAt: saleStart, EndsAt: saleEnd}},
+			at:         saleStart.Add(-time.Second),
+			want:       decimal.NewFromFloat(500.00),
+		},
+		{
+			name:       "flash sale ended",
+			promotions: []Promotion{{ProductID: "A", PromotionType: PercentageDiscount, Discount: 50, StartsAt: saleStart, EndsAt: saleEnd}},
+			at:         saleEnd,
+			want:       decimal.NewFromFloat(500.00),
+		},
+		{
+			name: "inactive exclusive promotion does not block a stackable one",
+			promotions: []Promotion{
+				{ProductID: "A", PromotionType: PercentageDiscount, Discount: 50, EndsAt: saleStart},
+				{ProductID: "A", PromotionType: PercentageDiscount, Discount: 10, Stacking: Stackable, Priority: 1},
+			},
+			at:   saleStart,
+			want: decimal.NewFromFloat(480.00),
+		},
+		{
+			name:       "happy hour on the cart",
+			promotions: []Promotion{{PromotionType: TotalDiscount, Discount: 20, Schedule: &Schedule{StartHour: 17, EndHour: 19}}},
+			at:         saleStart.Add(18 * time.Hour),
+			want:       decimal.NewFromFloat(400.00),
+		},
+		{
+			name:       "outside happy hour",
+			promotions: []Promotion{{PromotionType: TotalDiscount, Discount: 20, Schedule: &Schedule{StartHour: 17, EndHour: 19}}},
+			at:         saleStart.Add(20 * time.Hour),
+			want:       decimal.NewFromFloat(500.00),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart()
+			require.NoError(t, cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(100.00)}, 2))
+			require.NoError(t, cart.AddProduct(Product{ID: "B", Price: decimal.NewFromFloat(300.00)}, 1))
+			for _, promo := range tt.promotions {
+				require.NoError(t, ValidatePromotion(promo))
+				cart.AddPromotion(promo)
+			}
+
+			got := cart.CalculateTotalAt(tt.at)
+			assert.True(t, tt.want.Equal(got), "Expected %s, got %s", tt.want.String(), got.String())
+
+			cart.Clock = func() time.Time { return tt.at }
+			assert.True(t, got.Equal(cart.CalculateTotal()))
+		})
+	}
+}
+
+func TestCart_OptimizePromotions_Inactive(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cart := NewCart()
+	cart.Clock = func() time.Time { return now }
+	require.NoError(t, cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(100.00)}, 1))
+	cart.AddPromotion(Promotion{ID: "OLD", ProductID: "A", PromotionType: PercentageDiscount, Discount: 50, EndsAt: now})
+
+	decisions := cart.OptimizePromotions()
+
+	require.Len(t, decisions, 2)
+	require.Len(t, decisions[0].Decisions, 1)
+	assert.False(t, decisions[0].Decisions[0].Chosen)
+	assert.Equal(t, ReasonInactive, decisions[0].Decisions[0].Reason)
+	assert.True(t, decimal.NewFromFloat(100.00).Equal(decisions[0].Total))
+}
+
+func TestValidatePromotion_Schedule(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		promotion Promotion
+		errorMsg  string
+	}{
+		{
+			name:      "valid schedule",
+			promotion: Promotion{StartsAt: now, EndsAt: now.Add(time.Hour), Schedule: &Schedule{Weekdays: []time.Weekday{time.Monday}, StartHour: 17, EndHour: 24, Location: "Asia/Bangkok"}},
+		},
+		{
+			name:      "ends before it starts",
+			promotion: Promotion{StartsAt: now, EndsAt: now},
+			errorMsg:  "promotion must end after it starts",
+		},
+		{
+			name:      "hour out of range",
+			promotion: Promotion{Schedule: &Schedule{StartHour: 24}},
+			errorMsg:  "schedule hours must be between 0 and 24",
+		},
+		{
+			name:      "unknown weekday",
+			promotion: Promotion{Schedule: &Schedule{Weekdays: []time.Weekday{7}}},
+			errorMsg:  "unknown schedule weekday",
+		},
+		{
+			name:      "unknown time zone",
+			promotion: Promotion{Schedule: &Schedule{Location: "Mars/Olympus"}},
+			errorMsg:  "unknown schedule time zone",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promotion := tt.promotion
+			promotion.PromotionType = TotalDiscount
+			promotion.Discount = 10
+
+			err := ValidatePromotion(promotion)
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.errorMsg, err.Error())
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package cart
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrUnknownTaxRegion = errors.New("unknown tax region")
+
+// TaxCategory groups products that are taxed at the same rate
+type TaxCategory string
+
+const (
+	// TaxStandard is the category of products without one. It is the zero value.
+	TaxStandard TaxCategory = "standard"
+	TaxReduced  TaxCategory = "reduced"
+	TaxExempt   TaxCategory = "exempt"
+)
+
+// TaxMode selects whether prices already include tax
+type TaxMode string
+
+const (
+	// TaxExclusive adds tax on top of the prices. It is the zero value.
+	TaxExclusive TaxMode = "exclusive"
+	// TaxInclusive treats prices as already including tax, e.g. Thai VAT.
+	TaxInclusive TaxMode = "inclusive"
+)
+
+// TaxRounding selects where tax amounts are rounded to the currency precision
+type TaxRounding string
+
+const (
+	// RoundPerInvoice rounds the tax of each category once. It is the zero value.
+	RoundPerInvoice TaxRounding = "perInvoice"
+	// RoundPerLine rounds the tax of every line before summing.
+	RoundPerLine TaxRounding = "perLine"
+)
+
+type (
+	// TaxPolicy is how one region taxes a cart
+	TaxPolicy struct {
+		Region   string
+		Mode     TaxMode
+		Rounding TaxRounding
+		Rates    map[TaxCategory]decimal.Decimal // percentage per category, unknown categories use TaxStandard
+	}
+
+	// TaxTable holds the tax policy of each region
+	TaxTable map[string]TaxPolicy
+
+	// TaxLine is the tax charged for one category
+	TaxLine struct {
+		Category TaxCategory
+		Rate     decimal.Decimal // percentage
+		Net      decimal.Decimal // taxable amount excluding tax
+		Amount   decimal.Decimal
+	}
+
+	// taxableAmount is a priced part of the cart, after every discount
+	taxableAmount struct {
+		category TaxCategory
+		amount   decimal.Decimal
+	}
+)
+
+// DefaultTaxTable returns the built-in regions, Thailand with 7% VAT included in prices
+func DefaultTaxTable() TaxTable {
+	return TaxTable{
+		"TH": {
+			Region:   "TH",
+			Mode:     TaxInclusive,
+			Rounding: RoundPerInvoice,
+			Rates: map[TaxCategory]decimal.Decimal{
+				TaxStandard: decimal.NewFromInt(7),
+				TaxExempt:   decimal.Zero,
+			},
+		},
+	}
+}
+
+// Policy returns the tax policy of the region
+func (t TaxTable) Policy(region string) (TaxPolicy, error) {
+	policy, ok := t[region]
+	if !ok {
+		return TaxPolicy{}, fmt.Errorf("%w: %s", ErrUnknownTaxRegion, region)
+	}
+	return policy, nil
+}
+
+// RateFor returns the tax percentage of the category
+func (p TaxPolicy) RateFor(category TaxCategory) decimal.Decimal {
+	if category == "" {
+		category = TaxStandard
+	}
+	if rate, ok := p.Rates[category]; ok {
+		return rate
+	}
+	return p.Rates[TaxStandard]
+}
+
+// taxOf returns the tax contained in, or added to, the amount
+func (p TaxPolicy) taxOf(amount, rate decimal.Decimal) decimal.Decimal {
+	hundred := decimal.NewFromInt(100)
+	if p.Mode == TaxInclusive {
+		return amount.Mul(rate).Div(hundred.Add(rate))
+	}
+	return amount.Mul(rate).Div(hundred)
+}
+
+// calculateTax returns one tax line per category, sorted by category.
+// round brings tax amounts to the currency precision.
+func (p TaxPolicy) calculateTax(amounts []taxableAmount, round func(decimal.Decimal) decimal.Decimal) []TaxLine {
+	byCategory := make(map[TaxCategory]*TaxLine)
+	for _, taxable := range amounts {
+		category := taxable.category
+		if category == "" {
+			category = TaxStandard
+		}
+
+		line, ok := byCategory[category]
+		if !ok {
+			line = &TaxLine{Category: category, Rate: p.RateFor(category), Net: decimal.Zero, Amount: decimal.Zero}
+			byCategory[category] = line
+		}
+
+		// Net temporarily holds the amount as priced until the tax is known
+		line.Net = line.Net.Add(taxable.amount)
+		if p.Rounding == RoundPerLine {
+			line.Amount = line.Amount.Add(round(p.taxOf(taxable.amount, line.Rate)))
+		}
+	}
+
+	lines := make([]TaxLine, 0, len(byCategory))
+	for _, line := range byCategory {
+		if p.Rounding != RoundPerLine {
+			line.Amount = round(p.taxOf(line.Net, line.Rate))
+		}
+		if p.Mode == TaxInclusive {
+			line.Net = line.Net.Sub(line.Amount)
+		}
+		lines = append(lines, *line)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].Category < lines[j].Category
+	})
+
+	return lines
+}
+
+// taxableAmounts splits the pre-tax total over the lines and bundle components.
+// Bundles are shared by the list price of their components and cart-wide
+// promotions by the share of each part in the subtotal.
+func (c *Cart) taxableAmounts(b PriceBreakdown) []taxableAmount {
+	amounts := make([]taxableAmount, 0, len(b.Lines))
+	for _, line := range b.Lines {
+		amounts = append(amounts, taxableAmount{category: c.Items[line.SKU].Product.TaxCategory, amount: line.LineTotal})
+	}
+
+	for _, bundle := range b.Bundles {
+		gross := decimal.Zero
+		for _, component := range bundle.Components {
+			gross = gross.Add(c.componentGross(component))
+		}
+		for _, component := range bundle.Components {
+			share := bundle.Total.Div(decimal.NewFromInt(int64(len(bundle.Components))))
+			if gross.IsPositive() {
+				share = bundle.Total.Mul(c.componentGross(component)).Div(gross)
+			}
+			amounts = append(amounts, taxableAmount{category: c.Items[component.SKU].Product.TaxCategory, amount: share})
+		}
+	}
+
+	if b.Subtotal.IsPositive() && !b.Total.Equal(b.Subtotal) {
+		for i := range amounts {
+			amounts[i].amount = amounts[i].amount.Mul(b.Total).Div(b.Subtotal)
+		}
+	}
+
+	return amounts
+}
+
+func (c *Cart) componentGross(component BundleComponent) decimal.Decimal {
+	return c.Items[component.SKU].Product.Price.Mul(decimal.NewFromInt(component.Quantity))
+}
+
+// ValidateTaxPolicy validates tax policy data
+func ValidateTaxPolicy(policy TaxPolicy) error {
+	switch policy.Mode {
+	case "", TaxExclusive, TaxInclusive:
+	default:
+		return errors.New("unknown tax mode")
+	}
+
+	switch policy.Rounding {
+	case "", RoundPerInvoice, RoundPerLine:
+	default:
+		return errors.New("unknown tax rounding")
+	}
+
+	for _, rate := range policy.Rates {
+		if rate.IsNegative() || rate.GreaterThan(decimal.NewFromInt(100)) {
+			return errors.New("tax rate must be between 0 and 100")
+		}
+	}
+
+	return nil
+}
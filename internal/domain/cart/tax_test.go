@@ -0,0 +1,179 @@
+package cart
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCart_Breakdown_Tax(t *testing.T) {
+	thaiVAT := DefaultTaxTable()["TH"]
+	salesTax := TaxPolicy{
+		Region: "US-CA",
+		Mode:   TaxExclusive,
+		Rates: map[TaxCategory]decimal.Decimal{
+			TaxStandard: decimal.NewFromFloat(7.25),
+			TaxReduced:  decimal.NewFromInt(0),
+		},
+	}
+
+	tests := []struct {
+		name       string
+		policy     TaxPolicy
+		promotions []Promotion
+		wantTaxes  []TaxLine
+		wantTotal  decimal.Decimal
+	}{
+		{
+			name:   "thai VAT included in prices",
+			policy: thaiVAT,
+			wantTaxes: []TaxLine{
+				{Category: TaxExempt, Rate: decimal.Zero, Net: decimal.NewFromFloat(30.00), Amount: decimal.Zero},
+				{Category: TaxStandard, Rate: decimal.NewFromInt(7), Net: decimal.NewFromFloat(149.53), Amount: decimal.NewFromFloat(10.47)}, // 160 * 7 / 107
+			},
+			wantTotal: decimal.NewFromFloat(190.00),
+		},
+		{
+			// Exempt is not listed in the policy so the standard rate applies
+			name:   "tax added on top of prices",
+			policy: salesTax,
+			wantTaxes: []TaxLine{
+				{Category: TaxExempt, Rate: decimal.NewFromFloat(7.25), Net: decimal.NewFromFloat(30.00), Amount: decimal.NewFromFloat(2.18)},
+				{Category: TaxStandard, Rate: decimal.NewFromFloat(7.25), Net: decimal.NewFromFloat(160.00), Amount: decimal.NewFromFloat(11.60)},
+			},
+			wantTotal: decimal.NewFromFloat(203.78),
+		},
+		{
+			name:       "cart discount shared before tax",
+			policy:     thaiVAT,
+			promotions: []Promotion{{PromotionType: TotalDiscount, Discount: 50}},
+			wantTaxes: []TaxLine{
+				{Category: TaxExempt, Rate: decimal.Zero, Net: decimal.NewFromFloat(15.00), Amount: decimal.Zero},
+				{Category: TaxStandard, Rate: decimal.NewFromInt(7), Net: decimal.NewFromFloat(74.77), Amount: decimal.NewFromFloat(5.23)}, // 80 * 7 / 107
+			},
+			wantTotal: decimal.NewFromFloat(95.00),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart()
+			require.NoError(t, cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(100.00)}, 1))
+			require.NoError(t, cart.AddProduct(Product{ID: "B", Price: decimal.NewFromFloat(60.00), TaxCategory: TaxStandard}, 1))
+			require.NoError(t, cart.AddProduct(Product{ID: "C", Price: decimal.NewFromFloat(30.00), TaxCategory: TaxExempt}, 1))
+			for _, promo := range tt.promotions {
+				cart.AddPromotion(promo)
+			}
+			require.NoError(t, ValidateTaxPolicy(tt.policy))
+			cart.Tax = &tt.policy
+
+			got := cart.Breakdown()
+
+			require.Len(t, got.Taxes, len(tt.wantTaxes))
+			tax := decimal.Zero
+			for i, want := range tt.wantTaxes {
+				assert.Equal(t, want.Category, got.Taxes[i].Category)
+				assert.True(t, want.Rate.Equal(got.Taxes[i].Rate), "rate %s", got.Taxes[i].Rate)
+				assert.True(t, want.Net.Equal(got.Taxes[i].Net), "net %s", got.Taxes[i].Net)
+				assert.True(t, want.Amount.Equal(got.Taxes[i].Amount), "amount %s", got.Taxes[i].Amount)
+				tax = tax.Add(want.Amount)
+			}
+			assert.True(t, tax.Equal(got.Tax))
+			assert.True(t, tt.wantTotal.Equal(got.Total), "Expected %s, got %s", tt.wantTotal.String(), got.Total.String())
+		})
+	}
+}
+
+func TestCart_Breakdown_TaxRounding(t *testing.T) {
+	tests := []struct {
+		name     string
+		rounding TaxRounding
+		want     decimal.Decimal
+	}{
+		{name: "per invoice", rounding: RoundPerInvoice, want: decimal.NewFromFloat(0.20)}, // 3 * 0.95 * 7 / 100 = 0.1995
+		{name: "per line", rounding: RoundPerLine, want: decimal.NewFromFloat(0.21)},       // 3 * round(0.0665)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart()
+			for _, id := range []string{"A", "B", "C"} {
+				require.NoError(t, cart.AddProduct(Product{ID: id, Price: decimal.NewFromFloat(0.95)}, 1))
+			}
+			cart.Tax = &TaxPolicy{
+				Mode:     TaxExclusive,
+				Rounding: tt.rounding,
+				Rates:    map[TaxCategory]decimal.Decimal{TaxStandard: decimal.NewFromInt(7)},
+			}
+
+			got := cart.Breakdown()
+
+			assert.True(t, tt.want.Equal(got.Tax), "Expected %s, got %s", tt.want.String(), got.Tax.String())
+			assert.True(t, decimal.NewFromFloat(2.85).Add(tt.want).Equal(got.Total))
+		})
+	}
+}
+
+func TestCart_Breakdown_TaxBundle(t *testing.T) {
+	cart := NewCart()
+	require.NoError(t, cart.AddProduct(Product{ID: "A", Price: decimal.NewFromFloat(300.00)}, 1))
+	require.NoError(t, cart.AddProduct(Product{ID: "B", Price: decimal.NewFromFloat(100.00), TaxCategory: TaxExempt}, 1))
+	cart.AddPromotion(Promotion{
+		PromotionType: BundlePrice,
+		Amount:        decimal.NewFromFloat(214.00),
+		BundleItems:   []BundleItem{{ProductID: "A", Quantity: 1}, {ProductID: "B", Quantity: 1}},
+	})
+	policy := DefaultTaxTable()["TH"]
+	cart.Tax = &policy
+
+	got := cart.Breakdown()
+
+	// A takes 3/4 of the bundle price, 160.50 including 10.50 VAT
+	require.Len(t, got.Taxes, 2)
+	assert.True(t, decimal.NewFromFloat(53.50).Equal(got.Taxes[0].Net))
+	assert.True(t, decimal.NewFromFloat(10.50).Equal(got.Taxes[1].Amount))
+	assert.True(t, decimal.NewFromFloat(214.00).Equal(got.Total))
+}
+
+func TestTaxTable_Policy(t *testing.T) {
+	policy, err := DefaultTaxTable().Policy("TH")
+	require.NoError(t, err)
+	assert.Equal(t, TaxInclusive, policy.Mode)
+	assert.True(t, decimal.NewFromInt(7).Equal(policy.RateFor("")))
+	assert.True(t, decimal.NewFromInt(7).Equal(policy.RateFor("unknown")))
+	assert.True(t, decimal.Zero.Equal(policy.RateFor(TaxExempt)))
+
+	_, err = DefaultTaxTable().Policy("XX")
+	assert.ErrorIs(t, err, ErrUnknownTaxRegion)
+}
+
+func TestValidateTaxPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   TaxPolicy
+		errorMsg string
+	}{
+		{name: "valid policy", policy: DefaultTaxTable()["TH"]},
+		{name: "unknown mode", policy: TaxPolicy{Mode: "gross"}, errorMsg: "unknown tax mode"},
+		{name: "unknown rounding", policy: TaxPolicy{Rounding: "never"}, errorMsg: "unknown tax rounding"},
+		{
+			name:     "rate out of range",
+			policy:   TaxPolicy{Rates: map[TaxCategory]decimal.Decimal{TaxStandard: decimal.NewFromInt(101)}},
+			errorMsg: "tax rate must be between 0 and 100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTaxPolicy(tt.policy)
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.errorMsg, err.Error())
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package cart
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrVariantNotFound = errors.New("variant not found")
+
+// Variant is one purchasable version of a product, e.g. a shirt in size M and red
+type Variant struct {
+	SKU     string
+	Options map[string]string // option name -> value, e.g. "size" -> "M"
+	Price   *decimal.Decimal  // overrides Product.Price, nil keeps it
+	Stock   *int64            // units on hand when the product is created, nil leaves the SKU untracked
+}
+
+// Variant returns the product as sold under the SKU, with the variant's options
+// and price. The result has no variants and can be added to a cart.
+func (p Product) Variant(sku string) (Product, error) {
+	for _, variant := range p.Variants {
+		if variant.SKU != sku {
+			continue
+		}
+
+		product := p
+		product.SKU = variant.SKU
+		product.Options = variant.Options
+		if variant.Price != nil {
+			product.Price = *variant.Price
+		}
+		product.Variants = nil
+		return product, nil
+	}
+
+	return Product{}, fmt.Errorf("%w: %s", ErrVariantNotFound, sku)
+}
+
+// LineSKU returns the key of the product's cart line, products without variants
+// are sold under their ID
+func (p Product) LineSKU() string {
+	if p.SKU != "" {
+		return p.SKU
+	}
+	return p.ID
+}
+
+// matches reports whether a product promotion applies to the product, a promotion
+// without a SKU applies to every variant
+func (p *Promotion) matches(product Product) bool {
+	return p.SKU == "" || p.SKU == product.LineSKU()
+}
+
+// promotionsFor returns the product promotions that apply to the line's variant
+func (c *Cart) promotionsFor(product Product) []*Promotion {
+	promotions := c.Promotion[product.ID]
+	matching := make([]*Promotion, 0, len(promotions))
+	for _, promo := range promotions {
+		if promo.matches(product) {
+			matching = append(matching, promo)
+		}
+	}
+	return matching
+}
+
+// productQuantities sums the line quantities, keyed by SKU, per product ID
+func (c *Cart) productQuantities(lines map[string]int64) map[string]int64 {
+	quantities := make(map[string]int64, len(lines))
+	for sku, qty := range lines {
+		if item, ok := c.Items[sku]; ok {
+			quantities[item.Product.ID] += qty
+		}
+	}
+	return quantities
+}
+
+// skusOf returns the SKUs of the product's lines in order
+func (c *Cart) skusOf(productID string) []string {
+	var skus []string
+	for sku, item := range c.Items {
+		if item.Product.ID == productID {
+			skus = append(skus, sku)
+		}
+	}
+	sort.Strings(skus)
+	return skus
+}
+
+func validateVariants(product Product) error {
+	seen := make(map[string]bool, len(product.Variants))
+	for _, variant := range product.Variants {
+		if strings.TrimSpace(variant.SKU) == "" {
+			return errors.New("variant SKU cannot be empty")
+		}
+		if seen[variant.SKU] {
+			return errors.New("variant SKUs must be unique")
+		}
+		seen[variant.SKU] = true
+
+		if variant.Price != nil && variant.Price.IsNegative() {
+			return errors.New("variant price cannot be negative")
+		}
+		if variant.Stock != nil && *variant.Stock < 0 {
+			return errors.New("variant stock cannot be negative")
+		}
+		for name := range variant.Options {
+			if strings.TrimSpace(name) == "" {
+				return errors.New("variant option name cannot be empty")
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package cart
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newShirt returns a shirt at 500 in S and M, with M overriding the price to 550
+func newShirt() Product {
+	price := decimal.NewFromFloat(550.00)
+	return Product{
+		ID:    "SHIRT",
+		Price: decimal.NewFromFloat(500.00),
+		Variants: []Variant{
+			{SKU: "SHIRT-S", Options: map[string]string{"size": "S"}},
+			{SKU: "SHIRT-M", Options: map[string]string{"size": "M"}, Price: &price},
+		},
+	}
+}
+
+func TestProduct_Variant(t *testing.T) {
+	shirt := newShirt()
+
+	small, err := shirt.Variant("SHIRT-S")
+	require.NoError(t, err)
+	assert.Equal(t, "SHIRT", small.ID)
+	assert.Equal(t, "SHIRT-S", small.SKU)
+	assert.Equal(t, map[string]string{"size": "S"}, small.Options)
+	assert.True(t, decimal.NewFromFloat(500.00).Equal(small.Price))
+	assert.Empty(t, small.Variants)
+
+	medium, err := shirt.Variant("SHIRT-M")
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromFloat(550.00).Equal(medium.Price))
+
+	_, err = shirt.Variant("SHIRT-XL")
+	assert.ErrorIs(t, err, ErrVariantNotFound)
+}
+
+func TestCart_AddProduct_Variants(t *testing.T) {
+	shirt := newShirt()
+	small, err := shirt.Variant("SHIRT-S")
+	require.NoError(t, err)
+	medium, err := shirt.Variant("SHIRT-M")
+	require.NoError(t, err)
+
+	cart := NewCart()
+	err = cart.AddProduct(shirt, 1)
+	assert.ErrorIs(t, err, ErrInvalidProduct)
+
+	require.NoError(t, cart.AddProduct(small, 1))
+	require.NoError(t, cart.AddProduct(medium, 2))
+	require.NoError(t, cart.AddProduct(small, 1))
+	require.Len(t, cart.Items, 2)
+	assert.Equal(t, int64(2), cart.Items["SHIRT-S"].Quantity)
+	assert.Equal(t, int64(2), cart.Items["SHIRT-M"].Quantity)
+
+	got := cart.Breakdown()
+	require.Len(t, got.Lines, 2)
+	assert.Equal(t, "SHIRT-M", got.Lines[0].SKU)
+	assert.Equal(t, "SHIRT-S", got.Lines[1].SKU)
+	assert.True(t, decimal.NewFromFloat(2100.00).Equal(got.Total)) // 2 * 550 + 2 * 500
+}
+
+func TestCart_CalculateTotal_VariantPromotions(t *testing.T) {
+	tests := []struct {
+		name      string
+		promotion Promotion
+		want      decimal.Decimal
+	}{
+		{
+			name:      "product promotion applies to every variant",
+			promotion: Promotion{ProductID: "SHIRT", PromotionType: PercentageDiscount, Discount: 10},
+			want:      decimal.NewFromFloat(1890.00), // 2100 - 10%
+		},
+		{
+			name:      "variant promotion applies to its SKU only",
+			promotion: Promotion{ProductID: "SHIRT", SKU: "SHIRT-M", PromotionType: PercentageDiscount, Discount: 10},
+			want:      decimal.NewFromFloat(1990.00), // 2 * 495 + 2 * 500
+		},
+		{
+			name:      "buy one get one per line",
+			promotion: Promotion{ProductID: "SHIRT", PromotionType: Buy1Get1Free},
+			want:      decimal.NewFromFloat(1050.00), // 550 + 500
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shirt := newShirt()
+			small, err := shirt.Variant("SHIRT-S")
+			require.NoError(t, err)
+			medium, err := shirt.Variant("SHIRT-M")
+			require.NoError(t, err)
+
+			cart := NewCart()
+			require.NoError(t, cart.AddProduct(small, 2))
+			require.NoError(t, cart.AddProduct(medium, 2))
+			require.NoError(t, ValidatePromotion(tt.promotion))
+			cart.AddPromotion(tt.promotion)
+
+			got := cart.CalculateTotal()
+			assert.True(t, tt.want.Equal(got), "Expected %s, got %s", tt.want.String(), got.String())
+		})
+	}
+}
+
+func TestCart_Bundles_Variants(t *testing.T) {
+	shirt := newShirt()
+	small, err := shirt.Variant("SHIRT-S")
+	require.NoError(t, err)
+	medium, err := shirt.Variant("SHIRT-M")
+	require.NoError(t, err)
+
+	cart := NewCart()
+	require.NoError(t, cart.AddProduct(small, 1))
+	require.NoError(t, cart.AddProduct(medium, 1))
+	require.NoError(t, cart.AddProduct(Product{ID: "CAP", Price: decimal.NewFromFloat(200.00)}, 1))
+	cart.AddPromotion(Promotion{
+		ID:            "OUTFIT",
+		PromotionType: BundlePrice,
+		Amount:        decimal.NewFromFloat(1000.00),
+		BundleItems:   []BundleItem{{ProductID: "SHIRT", Quantity: 2}, {ProductID: "CAP", Quantity: 1}},
+	})
+
+	got := cart.Breakdown()
+	require.Len(t, got.Bundles, 1)
+	assert.Equal(t, []BundleComponent{
+		{ProductID: "SHIRT", SKU: "SHIRT-M", Quantity: 1},
+		{ProductID: "SHIRT", SKU: "SHIRT-S", Quantity: 1},
+		{ProductID: "CAP", SKU: "CAP", Quantity: 1},
+	}, got.Bundles[0].Components)
+	assert.True(t, decimal.NewFromFloat(250.00).Equal(got.Bundles[0].Saving)) // 550 + 500 + 200 - 1000
+	assert.True(t, decimal.NewFromFloat(1000.00).Equal(got.Total))
+}
+
+func TestCart_RemoveProduct_Variants(t *testing.T) {
+	shirt := newShirt()
+	small, err := shirt.Variant("SHIRT-S")
+	require.NoError(t, err)
+	medium, err := shirt.Variant("SHIRT-M")
+	require.NoError(t, err)
+
+	cart := NewCart()
+	require.NoError(t, cart.AddProduct(small, 1))
+	require.NoError(t, cart.AddProduct(medium, 1))
+	cart.AddPromotion(Promotion{ID: "P1", ProductID: "SHIRT", PromotionType: PercentageDiscount, Discount: 10})
+
+	require.NoError(t, cart.RemoveProduct("SHIRT-S"))
+	assert.Len(t, cart.Promotion["SHIRT"], 1, "promotion kept while a variant remains")
+	require.NoError(t, cart.RemoveProduct("SHIRT-M"))
+	assert.Empty(t, cart.Promotion)
+}
+
+func TestValidateProduct_Variants(t *testing.T) {
+	negative := decimal.NewFromFloat(-1.00)
+	negativeStock := int64(-1)
+
+	tests := []struct {
+		name     string
+		variants []Variant
+		errorMsg string
+	}{
+		{
+			name:     "valid variants",
+			variants: newShirt().Variants,
+		},
+		{
+			name:     "empty SKU",
+			variants: []Variant{{SKU: " "}},
+			errorMsg: "variant SKU cannot be empty",
+		},
+		{
+			name:     "duplicate SKU",
+			variants: []Variant{{SKU: "A"}, {SKU: "A"}},
+			errorMsg: "variant SKUs must be unique",
+		},
+		{
+			name:     "negative price",
+			variants: []Variant{{SKU: "A", Price: &negative}},
+			errorMsg: "variant price cannot be negative",
+		},
+		{
+			name:     "negative stock",
+			variants: []Variant{{SKU: "A", Stock: &negativeStock}},
+			errorMsg: "variant stock cannot be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProduct(Product{ID: "SHIRT", Price: decimal.NewFromFloat(500.00), Variants: tt.variants})
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.errorMsg, err.Error())
+		})
+	}
+}
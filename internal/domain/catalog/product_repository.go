@@ -0,0 +1,32 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+)
+
+var (
+	ErrProductNotFound = errors.New("product not found in catalog")
+	ErrProductExists   = errors.New("product already exists")
+)
+
+// Product is the catalog of products customers can buy. Carts take prices and
+// discounts from it rather than from the client.
+type Product interface {
+	// Create stores a new product under its ID
+	Create(ctx context.Context, product cart.Product) error
+
+	// GetByID retrieves a product by its ID
+	GetByID(ctx context.Context, productID string) (cart.Product, error)
+
+	// List returns every product sorted by ID
+	List(ctx context.Context) ([]cart.Product, error)
+
+	// Update replaces an existing product
+	Update(ctx context.Context, product cart.Product) error
+
+	// Delete removes a product by its ID
+	Delete(ctx context.Context, productID string) error
+}
@@ -0,0 +1,146 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrRateNotFound   = errors.New("exchange rate not found")
+	ErrNoBaseCurrency = errors.New("cart has no currency to convert from")
+)
+
+type (
+	// Rate converts one unit of From into Rate units of To
+	Rate struct {
+		From string
+		To   string
+		Rate decimal.Decimal
+		AsOf time.Time // when the provider published the rate
+	}
+
+	// ExchangeRateProvider looks up the current rate between two ISO 4217 currencies.
+	// Implementations must be safe for concurrent use.
+	ExchangeRateProvider interface {
+		Rate(ctx context.Context, from, to string) (Rate, error)
+	}
+
+	// QuoteLine is a cart line converted to the display currency
+	QuoteLine struct {
+		ProductID string
+		SKU       string
+		Quantity  int64
+		Measure   decimal.Decimal // amount of a measured line in Unit
+		Unit      cart.UnitOfMeasure
+		LineTotal decimal.Decimal
+	}
+
+	// QuoteBundle is a bundle of the cart converted to the display currency
+	QuoteBundle struct {
+		PromotionID string
+		Count       int64 // complete bundles formed
+		Total       decimal.Decimal
+	}
+
+	// Quote shows a cart in a display currency. Display amounts are informational:
+	// checkout must charge Charge, which stays in the cart's base currency.
+	Quote struct {
+		Currency string // display currency
+		Rate     Rate
+		Lines    []QuoteLine
+		Bundles  []QuoteBundle // bundle prices, part of Subtotal like the lines
+		Subtotal decimal.Decimal
+		Tax      decimal.Decimal
+		Total    decimal.Decimal
+		Charge   cart.Money // grand total in the base currency
+	}
+)
+
+// Convert converts an amount with the rate, without rounding
+func (r Rate) Convert(amount decimal.Decimal) decimal.Decimal {
+	return amount.Mul(r.Rate)
+}
+
+// QuoteCart prices the cart in its base currency and converts the result to the
+// display currency. Every display amount is converted from the rounded base amount
+// and rounded again to the display currency with the cart's rounding mode.
+// The charge and the display amounts come from pricing the cart once.
+func QuoteCart(ctx context.Context, provider ExchangeRateProvider, c *cart.Cart, currency string) (Quote, error) {
+	currency = strings.ToUpper(currency)
+	if _, err := cart.LookupCurrency(currency); err != nil {
+		return Quote{}, err
+	}
+	if c.Currency == "" {
+		return Quote{}, ErrNoBaseCurrency
+	}
+
+	if err := c.CheckCurrency(); err != nil {
+		return Quote{}, err
+	}
+
+	rate := Rate{From: c.Currency, To: currency, Rate: decimal.NewFromInt(1)}
+	if currency != c.Currency {
+		var err error
+		rate, err = provider.Rate(ctx, c.Currency, currency)
+		if err != nil {
+			return Quote{}, err
+		}
+	}
+
+	convert := func(amount decimal.Decimal) decimal.Decimal {
+		return cart.NewMoney(rate.Convert(amount), currency).Round(c.Rounding).Amount
+	}
+
+	// A promotion that starts or ends between two pricings would split the charge from the display amounts
+	breakdown := c.Breakdown()
+	quote := Quote{
+		Currency: currency,
+		Rate:     rate,
+		Lines:    make([]QuoteLine, 0, len(breakdown.Lines)),
+		Bundles:  make([]QuoteBundle, 0, len(breakdown.Bundles)),
+		Subtotal: convert(breakdown.Subtotal),
+		Tax:      convert(breakdown.Tax),
+		Total:    convert(breakdown.Total),
+		Charge:   cart.NewMoney(breakdown.Total, c.Currency),
+	}
+	for _, line := range breakdown.Lines {
+		quote.Lines = append(quote.Lines, QuoteLine{
+			ProductID: line.ProductID,
+			SKU:       line.SKU,
+			Quantity:  line.Quantity,
+			Measure:   line.Measure,
+			Unit:      line.Unit,
+			LineTotal: convert(line.LineTotal),
+		})
+	}
+	for _, bundle := range breakdown.Bundles {
+		quote.Bundles = append(quote.Bundles, QuoteBundle{
+			PromotionID: bundle.PromotionID,
+			Count:       bundle.Count,
+			Total:       convert(bundle.Total),
+		})
+	}
+
+	return quote, nil
+}
+
+// ValidateRate validates rate data
+func ValidateRate(rate Rate) error {
+	if _, err := cart.LookupCurrency(rate.From); err != nil {
+		return fmt.Errorf("rate from: %w", err)
+	}
+	if _, err := cart.LookupCurrency(rate.To); err != nil {
+		return fmt.Errorf("rate to: %w", err)
+	}
+	if !rate.Rate.IsPositive() {
+		return errors.New("exchange rate must be positive")
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedProvider map[string]Rate
+
+func (p fixedProvider) Rate(ctx context.Context, from, to string) (Rate, error) {
+	rate, ok := p[from+to]
+	if !ok {
+		return Rate{}, ErrRateNotFound
+	}
+	return rate, nil
+}
+
+func TestQuoteCart(t *testing.T) {
+	asOf := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	provider := fixedProvider{
+		"THBUSD": {From: "THB", To: "USD", Rate: decimal.RequireFromString("0.0307"), AsOf: asOf},
+		"THBJPY": {From: "THB", To: "JPY", Rate: decimal.RequireFromString("4.41"), AsOf: asOf},
+	}
+
+	newCart := func(t *testing.T) *cart.Cart {
+		c := cart.NewCart()
+		require.NoError(t, c.AddProduct(cart.Product{ID: "A", Price: decimal.NewFromFloat(333.33), Currency: "THB"}, 1))
+		require.NoError(t, c.AddProduct(cart.Product{ID: "B", Price: decimal.NewFromFloat(100.00), Currency: "THB"}, 2))
+		return c
+	}
+
+	tests := []struct {
+		name      string
+		currency  string
+		wantTotal string
+		wantLines []string
+		wantErr   error
+	}{
+		{
+			name:      "converted and rounded to cents",
+			currency:  "usd",
+			wantTotal: "16.37", // 533.33 * 0.0307
+			wantLines: []string{"10.23", "6.14"},
+		},
+		{
+			name:      "no minor unit",
+			currency:  "JPY",
+			wantTotal: "2352",
+			wantLines: []string{"1470", "882"},
+		},
+		{
+			name:      "base currency needs no rate",
+			currency:  "THB",
+			wantTotal: "533.33",
+			wantLines: []string{"333.33", "200"},
+		},
+		{
+			name:     "missing rate",
+			currency: "EUR",
+			wantErr:  ErrRateNotFound,
+		},
+		{
+			name:     "unknown currency",
+			currency: "XXX",
+			wantErr:  cart.ErrUnknownCurrency,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCart(t)
+			got, err := QuoteCart(context.Background(), provider, c, tt.currency)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.True(t, decimal.RequireFromString(tt.wantTotal).Equal(got.Total), "Expected %s, got %s", tt.wantTotal, got.Total)
+			require.Len(t, got.Lines, len(tt.wantLines))
+			for i, want := range tt.wantLines {
+				assert.True(t, decimal.RequireFromString(want).Equal(got.Lines[i].LineTotal), "line %d: expected %s, got %s", i, want, got.Lines[i].LineTotal)
+			}
+
+			// Checkout always charges the base currency
+			assert.Equal(t, "THB", got.Charge.Currency)
+			assert.True(t, decimal.NewFromFloat(533.33).Equal(got.Charge.Amount))
+		})
+	}
+}
+
+func TestQuoteCart_RateTimestamp(t *testing.T) {
+	asOf := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	provider := fixedProvider{"THBUSD": {From: "THB", To: "USD", Rate: decimal.RequireFromString("0.03"), AsOf: asOf}}
+
+	c := cart.NewCart()
+	require.NoError(t, c.AddProduct(cart.Product{ID: "A", Price: decimal.NewFromFloat(100.00), Currency: "THB"}, 1))
+
+	got, err := QuoteCart(context.Background(), provider, c, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", got.Currency)
+	assert.Equal(t, asOf, got.Rate.AsOf)
+}
+
+func TestQuoteCart_NoBaseCurrency(t *testing.T) {
+	c := cart.NewCart()
+	require.NoError(t, c.AddProduct(cart.Product{ID: "A", Price: decimal.NewFromFloat(100.00)}, 1))
+
+	_, err := QuoteCart(context.Background(), fixedProvider{}, c, "USD")
+	assert.ErrorIs(t, err, ErrNoBaseCurrency)
+}
+
+func TestQuoteCart_PricedOnce(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := cart.NewCart()
+	require.NoError(t, c.AddProduct(cart.Product{ID: "A", Price: decimal.NewFromFloat(100.00), Currency: "THB"}, 1))
+	c.AddPromotion(cart.Promotion{ID: "FLASH", PromotionType: cart.TotalDiscount, Discount: 50, StartsAt: start})
+
+	// Every reading of the clock is a minute later, the flash sale starts during the quote
+	now := start.Add(-time.Minute)
+	c.Clock = func() time.Time {
+		at := now
+		now = now.Add(time.Minute)
+		return at
+	}
+
+	got, err := QuoteCart(context.Background(), fixedProvider{}, c, "THB")
+	require.NoError(t, err)
+	assert.True(t, got.Charge.Amount.Equal(got.Total), "charge %s, total %s", got.Charge, got.Total)
+	assert.True(t, decimal.NewFromInt(100).Equal(got.Total), "got %s", got.Total)
+}
+
+func TestQuoteCart_Bundles(t *testing.T) {
+	provider := fixedProvider{"THBUSD": {From: "THB", To: "USD", Rate: decimal.RequireFromString("0.03")}}
+
+	c := cart.NewCart()
+	require.NoError(t, c.AddProduct(cart.Product{ID: "A", Price: decimal.NewFromFloat(200.00), Currency: "THB"}, 2))
+	require.NoError(t, c.AddProduct(cart.Product{ID: "B", Price: decimal.NewFromFloat(100.00), Currency: "THB"}, 1))
+	c.AddPromotion(cart.Promotion{
+		ID:            "KIT",
+		PromotionType: cart.BundlePrice,
+		Amount:        decimal.NewFromFloat(250.00),
+		BundleItems:   []cart.BundleItem{{ProductID: "A", Quantity: 1}, {ProductID: "B", Quantity: 1}},
+	})
+
+	got, err := QuoteCart(context.Background(), provider, c, "USD")
+	require.NoError(t, err)
+
+	require.Len(t, got.Bundles, 1)
+	assert.Equal(t, "KIT", got.Bundles[0].PromotionID)
+	assert.Equal(t, int64(1), got.Bundles[0].Count)
+	assert.True(t, decimal.NewFromFloat(7.50).Equal(got.Bundles[0].Total), "got %s", got.Bundles[0].Total)
+
+	// The leftover unit of A and the bundle make up the subtotal
+	sum := got.Bundles[0].Total
+	for _, line := range got.Lines {
+		sum = sum.Add(line.LineTotal)
+	}
+	assert.True(t, decimal.NewFromFloat(13.50).Equal(got.Subtotal), "got %s", got.Subtotal)
+	assert.True(t, got.Subtotal.Equal(sum), "lines and bundles %s, subtotal %s", sum, got.Subtotal)
+}
@@ -0,0 +1,80 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrOutOfStock = errors.New("out of stock")
+
+// DefaultReservationTTL is how long a cart holds its units without being updated.
+// Every update of the cart holds all of its units for another TTL.
+const DefaultReservationTTL = 30 * time.Minute
+
+type (
+	// Level is the stock of one SKU
+	Level struct {
+		SKU      string
+		OnHand   int64 // units in the warehouse
+		Reserved int64 // units held by carts, expired reservations excluded
+	}
+
+	// OutOfStockError is returned when a reservation asks for more than is available.
+	// It matches ErrOutOfStock with errors.Is.
+	OutOfStockError struct {
+		SKU       string
+		Requested int64
+		Available int64
+	}
+)
+
+func (e *OutOfStockError) Error() string {
+	return fmt.Sprintf("%s is out of stock: requested %d, available %d", e.SKU, e.Requested, e.Available)
+}
+
+func (e *OutOfStockError) Is(target error) bool {
+	return target == ErrOutOfStock
+}
+
+// Available returns the units that can still be reserved
+func (l Level) Available() int64 {
+	return max(l.OnHand-l.Reserved, 0)
+}
+
+// Stock tracks units per SKU and the soft reservations carts hold on them.
+// SKUs without a stock level are not tracked and can always be reserved.
+type Stock interface {
+	// SetStock sets the units on hand of a SKU and starts tracking it
+	SetStock(ctx context.Context, sku string, onHand int64) error
+
+	// Level returns the stock of a tracked SKU, ok is false for untracked SKUs
+	Level(ctx context.Context, sku string) (level Level, ok bool, err error)
+
+	// Reserve sets the units of the SKU the cart holds until expiresAt, replacing
+	// its previous reservation. It fails with an *OutOfStockError when other carts
+	// leave too few units.
+	Reserve(ctx context.Context, cartID, sku string, quantity int64, expiresAt time.Time) error
+
+	// Extend holds every unexpired reservation of the cart until expiresAt
+	Extend(ctx context.Context, cartID string, expiresAt time.Time) error
+
+	// Release drops the cart's reservation of the SKU
+	Release(ctx context.Context, cartID, sku string) error
+
+	// ReleaseCart drops every reservation of the cart
+	ReleaseCart(ctx context.Context, cartID string) error
+
+	// ReleaseExpired drops the reservations that expired by now and returns how many
+	ReleaseExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// ValidateStock validates a units on hand value
+func ValidateStock(onHand int64) error {
+	if onHand < 0 {
+		return errors.New("stock cannot be negative")
+	}
+
+	return nil
+}
@@ -2,10 +2,39 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/pkittipat/try-cart/internal/domain/cart"
 )
 
+var (
+	ErrCartNotFound  = errors.New("cart not found")
+	ErrCartExists    = errors.New("cart already exists")
+	ErrInvalidCartID = errors.New("invalid cart ID")
+	ErrInvalidUserID = errors.New("invalid user ID")
+
+	ErrVersionConflict = errors.New("cart version conflict")
+)
+
+// VersionConflictError is returned when a cart was updated after the version the
+// caller expected. It matches ErrVersionConflict with errors.Is.
+type VersionConflictError struct {
+	CartID   string
+	Expected int64
+	Current  int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("cart %s is at version %d, expected %d", e.CartID, e.Current, e.Expected)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// Cart stores carts. The carts it returns are the caller's copies, and a cart passed
+// to Update is stored as it is at the call; later changes need another Update.
 type Cart interface {
 	// Create creates a new cart and returns the cart ID
 	Create(ctx context.Context, userID string) (string, error)
@@ -16,11 +45,29 @@ type Cart interface {
 	// GetByUserID retrieves a cart by user ID
 	GetByUserID(ctx context.Context, userID string) (*cart.Cart, error)
 	
+	// GetOwnerID returns the ID of the user the cart was created for
+	GetOwnerID(ctx context.Context, cartID string) (string, error)
+
+	// GetByIDWithVersion retrieves a cart by its ID with its version. A cart starts
+	// at version 1 and every update adds one.
+	GetByIDWithVersion(ctx context.Context, cartID string) (*cart.Cart, int64, error)
+
+	// GetByUserIDWithVersion retrieves the user's cart with its ID and version
+	GetByUserIDWithVersion(ctx context.Context, userID string) (string, *cart.Cart, int64, error)
+
 	// Update updates an existing cart
 	Update(ctx context.Context, cartID string, cart *cart.Cart) error
+
+	// UpdateIfVersion updates the cart only if it is still at expectedVersion and
+	// returns its new version. It fails with a *VersionConflictError otherwise.
+	UpdateIfVersion(ctx context.Context, cartID string, cart *cart.Cart, expectedVersion int64) (int64, error)
 	
 	// Delete removes a cart by its ID
 	Delete(ctx context.Context, cartID string) error
+
+	// DeleteIfVersion removes the cart only if it is still at expectedVersion.
+	// It fails with a *VersionConflictError otherwise.
+	DeleteIfVersion(ctx context.Context, cartID string, expectedVersion int64) error
 	
 	// Exists checks if a cart exists by ID
 	Exists(ctx context.Context, cartID string) (bool, error)
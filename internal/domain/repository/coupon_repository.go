@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+)
+
+var (
+	ErrCouponNotFound = errors.New("coupon not found")
+	ErrCouponExists   = errors.New("coupon already exists")
+)
+
+type Coupon interface {
+	// Create stores a new coupon under its normalized code
+	Create(ctx context.Context, coupon cart.Coupon) error
+
+	// GetByCode retrieves a coupon by its code, ignoring case and surrounding spaces
+	GetByCode(ctx context.Context, code string) (cart.Coupon, error)
+
+	// Redeem records one use of the coupon by the user.
+	// It fails with a cart.CouponRejection when a usage limit has been reached.
+	Redeem(ctx context.Context, code string, userID string) error
+
+	// CancelRedemption takes back one use of the coupon by the user, for a redemption
+	// whose cart could not be stored. It does nothing when the user has none left.
+	CancelRedemption(ctx context.Context, code string, userID string) error
+}
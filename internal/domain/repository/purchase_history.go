@@ -0,0 +1,13 @@
+package repository
+
+import "context"
+
+// PurchaseHistory holds the units of each product users have bought, which
+// Product.MaxPerCustomer counts along with what the user has in the cart
+type PurchaseHistory interface {
+	// Purchased returns product ID -> units the user bought, empty when there are none
+	Purchased(ctx context.Context, userID string) (map[string]int64, error)
+
+	// Record adds units, product ID -> units, to what the user bought
+	Record(ctx context.Context, userID string, units map[string]int64) error
+}
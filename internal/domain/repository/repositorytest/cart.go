@@ -0,0 +1,933 @@
+// Package repositorytest checks that repository implementations follow the
+// contracts of the repository package.
+package repositorytest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/pkittipat/try-cart/internal/domain/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCart runs the repository.Cart conformance suite. newRepo must return an
+// empty repository on every call; the in-memory repository is the reference the
+// suite was written against.
+func TestCart(t *testing.T, newRepo func() repository.Cart) {
+	t.Run("Create", func(t *testing.T) { testCreate(t, newRepo) })
+	t.Run("GetByID", func(t *testing.T) { testGetByID(t, newRepo) })
+	t.Run("GetByUserID", func(t *testing.T) { testGetByUserID(t, newRepo) })
+	t.Run("GetOwnerID", func(t *testing.T) { testGetOwnerID(t, newRepo) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, newRepo) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newRepo) })
+	t.Run("Exists", func(t *testing.T) { testExists(t, newRepo) })
+	t.Run("Copies", func(t *testing.T) { testCopies(t, newRepo) })
+	t.Run("Versions", func(t *testing.T) { testVersions(t, newRepo) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, newRepo) })
+}
+
+func testCreate(t *testing.T, newRepo func() repository.Cart) {
+	tests := []struct {
+		name       string
+		userID     string
+		setupFunc  func(*testing.T, repository.Cart) string
+		wantErr    error
+		wantCartID bool
+	}{
+		{
+			name:       "successful creation",
+			userID:     "user123",
+			wantErr:    nil,
+			wantCartID: true,
+		},
+		{
+			name:       "empty userID",
+			userID:     "",
+			wantErr:    repository.ErrInvalidUserID,
+			wantCartID: false,
+		},
+		{
+			name:   "user already has cart",
+			userID: "existing_user",
+			setupFunc: func(t *testing.T, r repository.Cart) string {
+				return createCart(t, r, "existing_user")
+			},
+			wantErr:    repository.ErrCartExists,
+			wantCartID: true,
+		},
+		{
+			name:   "user's cart was deleted",
+			userID: "returning_user",
+			setupFunc: func(t *testing.T, r repository.Cart) string {
+				require.NoError(t, r.Delete(context.Background(), createCart(t, r, "returning_user")))
+				return ""
+			},
+			wantErr:    nil,
+			wantCartID: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo()
+			ctx := context.Background()
+
+			var existingID string
+			if tt.setupFunc != nil {
+				existingID = tt.setupFunc(t, repo)
+			}
+
+			cartID, err := repo.Create(ctx, tt.userID)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if !tt.wantCartID {
+				assert.Empty(t, cartID)
+				return
+			}
+			assert.NotEmpty(t, cartID)
+			if existingID != "" {
+				// A user's second Create returns the cart they already have
+				assert.Equal(t, existingID, cartID)
+			}
+			exists, err := repo.Exists(ctx, cartID)
+			require.NoError(t, err)
+			assert.True(t, exists)
+		})
+	}
+}
+
+func testGetByID(t *testing.T, newRepo func() repository.Cart) {
+	tests := []struct {
+		name      string
+		cartID    string
+		setupFunc func(*testing.T, repository.Cart) string
+		wantErr   error
+		wantCart  bool
+	}{
+		{
+			name: "successful retrieval",
+			setupFunc: func(t *testing.T, r repository.Cart) string {
+				return createCart(t, r, "user123")
+			},
+			wantErr:  nil,
+			wantCart: true,
+		},
+		{
+			name:     "empty cartID",
+			cartID:   "",
+			wantErr:  repository.ErrInvalidCartID,
+			wantCart: false,
+		},
+		{
+			name:     "non-existent cart",
+			cartID:   "non_existent",
+			wantErr:  repository.ErrCartNotFound,
+			wantCart: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo()
+			ctx := context.Background()
+
+			cartID := tt.cartID
+			if tt.setupFunc != nil {
+				cartID = tt.setupFunc(t, repo)
+			}
+
+			result, err := repo.GetByID(ctx, cartID)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if !tt.wantCart {
+				assert.Nil(t, result)
+				return
+			}
+			// A new cart is empty and ready to use
+			require.NotNil(t, result)
+			assert.Empty(t, result.Items)
+			assert.NotNil(t, result.Items)
+			assert.NotNil(t, result.Promotion)
+			assert.NotNil(t, result.Coupons)
+		})
+	}
+}
+
+func testGetByUserID(t *testing.T, newRepo func() repository.Cart) {
+	tests := []struct {
+		name      string
+		userID    string
+		setupFunc func(*testing.T, repository.Cart)
+		wantErr   error
+		wantCart  bool
+	}{
+		{
+			name:   "successful retrieval",
+			userID: "user123",
+			setupFunc: func(t *testing.T, r repository.Cart) {
+				createCart(t, r, "user123")
+				createCart(t, r, "user456")
+			},
+			wantErr:  nil,
+			wantCart: true,
+		},
+		{
+			name:     "empty userID",
+			userID:   "",
+			wantErr:  repository.ErrInvalidUserID,
+			wantCart: false,
+		},
+		{
+			name:     "user not found",
+			userID:   "non_existent_user",
+			wantErr:  repository.ErrCartNotFound,
+			wantCart: false,
+		},
+		{
+			name:   "user's cart was deleted",
+			userID: "deleted_user",
+			setupFunc: func(t *testing.T, r repository.Cart) {
+				require.NoError(t, r.Delete(context.Background(), createCart(t, r, "deleted_user")))
+			},
+			wantErr:  repository.ErrCartNotFound,
+			wantCart: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo()
+			ctx := context.Background()
+
+			if tt.setupFunc != nil {
+				tt.setupFunc(t, repo)
+			}
+
+			result, err := repo.GetByUserID(ctx, tt.userID)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if tt.wantCart {
+				assert.NotNil(t, result)
+			} else {
+				assert.Nil(t, result)
+			}
+		})
+	}
+}
+
+func testGetOwnerID(t *testing.T, newRepo func() repository.Cart) {
+	tests := []struct {
+		name      string
+		cartID    string
+		setupFunc func(*testing.T, repository.Cart) string
+		wantOwner string
+		wantErr   error
+	}{
+		{
+			name: "owner of the cart",
+			setupFunc: func(t *testing.T, r repository.Cart) string {
+				createCart(t, r, "user456")
+				return createCart(t, r, "user123")
+			},
+			wantOwner: "user123",
+		},
+		{
+			name:    "empty cartID",
+			cartID:  "",
+			wantErr: repository.ErrInvalidCartID,
+		},
+		{
+			name:    "non-existent cart",
+			cartID:  "non_existent",
+			wantErr: repository.ErrCartNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo()
+
+			cartID := tt.cartID
+			if tt.setupFunc != nil {
+				cartID = tt.setupFunc(t, repo)
+			}
+
+			owner, err := repo.GetOwnerID(context.Background(), cartID)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOwner, owner)
+		})
+	}
+}
+
+func testUpdate(t *testing.T, newRepo func() repository.Cart) {
+	tests := []struct {
+		name        string
+		cartID      string
+		updatedCart func(*testing.T) *cart.Cart
+		setupFunc   func(*testing.T, repository.Cart) string
+		wantErr     error
+	}{
+		{
+			name: "successful update",
+			updatedCart: func(*testing.T) *cart.Cart {
+				c := cart.NewCart()
+				c.AddProduct(cart.Product{ID: "A", Price: decimal.NewFromFloat(10.00)}, 2)
+				return c
+			},
+			setupFunc: func(t *testing.T, r repository.Cart) string {
+				return createCart(t, r, "user123")
+			},
+		},
+		{
+			name:        "every stored field",
+			updatedCart: newFullCart,
+			setupFunc: func(t *testing.T, r repository.Cart) string {
+				return createCart(t, r, "user123")
+			},
+		},
+		{
+			name: "lines and promotions are replaced",
+			updatedCart: func(t *testing.T) *cart.Cart {
+				c := newFullCart(t)
+				c.Clear()
+				c.RemovePromotion("SUMMER")
+				return c
+			},
+			setupFunc: func(t *testing.T, r repository.Cart) string {
+				cartID := createCart(t, r, "user123")
+				require.NoError(t, r.Update(context.Background(), cartID, newFullCart(t)))
+				return cartID
+			},
+		},
+		{
+			name:        "empty cartID",
+			cartID:      "",
+			updatedCart: func(*testing.T) *cart.Cart { return cart.NewCart() },
+			wantErr:     repository.ErrInvalidCartID,
+		},
+		{
+			name:        "non-existent cart",
+			cartID:      "non_existent",
+			updatedCart: func(*testing.T) *cart.Cart { return cart.NewCart() },
+			wantErr:     repository.ErrCartNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo()
+			ctx := context.Background()
+
+			cartID := tt.cartID
+			if tt.setupFunc != nil {
+				cartID = tt.setupFunc(t, repo)
+			}
+			want := tt.updatedCart(t)
+
+			err := repo.Update(ctx, cartID, want)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			// Verify the update took effect
+			got, err := repo.GetByID(ctx, cartID)
+			require.NoError(t, err)
+			assertSameCart(t, want, got)
+			assert.True(t, want.CalculateTotal().Equal(got.CalculateTotal()))
+		})
+	}
+
+	t.Run("nil cart", func(t *testing.T) {
+		repo := newRepo()
+		cartID := createCart(t, repo, "user123")
+
+		assert.Error(t, repo.Update(context.Background(), cartID, nil))
+	})
+
+	t.Run("other carts are untouched", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		cartID := createCart(t, repo, "user123")
+		otherID := createCart(t, repo, "user456")
+
+		require.NoError(t, repo.Update(ctx, cartID, newFullCart(t)))
+
+		other, err := repo.GetByUserID(ctx, "user456")
+		require.NoError(t, err)
+		assert.Empty(t, other.Items)
+		got, err := repo.GetByID(ctx, otherID)
+		require.NoError(t, err)
+		assert.Empty(t, got.Items)
+	})
+}
+
+func testDelete(t *testing.T, newRepo func() repository.Cart) {
+	tests := []struct {
+		name      string
+		cartID    string
+		setupFunc func(*testing.T, repository.Cart) string
+		wantErr   error
+	}{
+		{
+			name: "successful deletion",
+			setupFunc: func(t *testing.T, r repository.Cart) string {
+				return createCart(t, r, "user123")
+			},
+			wantErr: nil,
+		},
+		{
+			name: "cart with lines and promotions",
+			setupFunc: func(t *testing.T, r repository.Cart) string {
+				cartID := createCart(t, r, "user123")
+				require.NoError(t, r.Update(context.Background(), cartID, newFullCart(t)))
+				return cartID
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "empty cartID",
+			cartID:  "",
+			wantErr: repository.ErrInvalidCartID,
+		},
+		{
+			name:    "non-existent cart",
+			cartID:  "non_existent",
+			wantErr: repository.ErrCartNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo()
+			ctx := context.Background()
+
+			cartID := tt.cartID
+			if tt.setupFunc != nil {
+				cartID = tt.setupFunc(t, repo)
+			}
+
+			err := repo.Delete(ctx, cartID)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			// Verify deletion
+			_, err = repo.GetByID(ctx, cartID)
+			assert.ErrorIs(t, err, repository.ErrCartNotFound)
+			_, err = repo.GetByUserID(ctx, "user123")
+			assert.ErrorIs(t, err, repository.ErrCartNotFound)
+			assert.ErrorIs(t, repo.Delete(ctx, cartID), repository.ErrCartNotFound)
+			assert.ErrorIs(t, repo.Update(ctx, cartID, cart.NewCart()), repository.ErrCartNotFound)
+		})
+	}
+}
+
+func testExists(t *testing.T, newRepo func() repository.Cart) {
+	tests := []struct {
+		name       string
+		cartID     string
+		setupFunc  func(*testing.T, repository.Cart) string
+		wantExists bool
+		wantErr    error
+	}{
+		{
+			name: "existing cart",
+			setupFunc: func(t *testing.T, r repository.Cart) string {
+				return createCart(t, r, "user123")
+			},
+			wantExists: true,
+			wantErr:    nil,
+		},
+		{
+			name: "deleted cart",
+			setupFunc: func(t *testing.T, r repository.Cart) string {
+				cartID := createCart(t, r, "user123")
+				require.NoError(t, r.Delete(context.Background(), cartID))
+				return cartID
+			},
+			wantExists: false,
+			wantErr:    nil,
+		},
+		{
+			name:       "non-existent cart",
+			cartID:     "non_existent",
+			wantExists: false,
+			wantErr:    nil,
+		},
+		{
+			name:       "empty cartID",
+			cartID:     "",
+			wantExists: false,
+			wantErr:    repository.ErrInvalidCartID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo()
+			ctx := context.Background()
+
+			cartID := tt.cartID
+			if tt.setupFunc != nil {
+				cartID = tt.setupFunc(t, repo)
+			}
+
+			exists, err := repo.Exists(ctx, cartID)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantExists, exists)
+		})
+	}
+}
+
+func testCopies(t *testing.T, newRepo func() repository.Cart) {
+	product := cart.Product{ID: "A", Price: decimal.NewFromInt(10)}
+	reads := []struct {
+		name string
+		get  func(repository.Cart, string) (*cart.Cart, error)
+	}{
+		{
+			name: "GetByID",
+			get: func(r repository.Cart, cartID string) (*cart.Cart, error) {
+				return r.GetByID(context.Background(), cartID)
+			},
+		},
+		{
+			name: "GetByUserID",
+			get: func(r repository.Cart, _ string) (*cart.Cart, error) {
+				return r.GetByUserID(context.Background(), "user123")
+			},
+		},
+		{
+			name: "GetByIDWithVersion",
+			get: func(r repository.Cart, cartID string) (*cart.Cart, error) {
+				c, _, err := r.GetByIDWithVersion(context.Background(), cartID)
+				return c, err
+			},
+		},
+		{
+			name: "GetByUserIDWithVersion",
+			get: func(r repository.Cart, _ string) (*cart.Cart, error) {
+				_, c, _, err := r.GetByUserIDWithVersion(context.Background(), "user123")
+				return c, err
+			},
+		},
+	}
+
+	for _, tt := range reads {
+		t.Run("changing a cart read with "+tt.name, func(t *testing.T) {
+			repo := newRepo()
+			cartID := createCart(t, repo, "user123")
+
+			c, err := tt.get(repo, cartID)
+			require.NoError(t, err)
+			require.NoError(t, c.AddProduct(product, 1))
+			c.AddPromotion(cart.Promotion{ID: "A10", ProductID: "A", PromotionType: cart.PercentageDiscount, Discount: 10})
+
+			got, err := tt.get(repo, cartID)
+			require.NoError(t, err)
+			assert.Empty(t, got.Items)
+			assert.Empty(t, got.Promotion)
+		})
+	}
+
+	t.Run("changing a cart after Update", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		cartID := createCart(t, repo, "user123")
+
+		c := cart.NewCart()
+		require.NoError(t, c.AddProduct(product, 1))
+		require.NoError(t, repo.Update(ctx, cartID, c))
+		require.NoError(t, c.SetQuantity("A", 5))
+
+		got, err := repo.GetByID(ctx, cartID)
+		require.NoError(t, err)
+		require.Contains(t, got.Items, "A")
+		assert.Equal(t, int64(1), got.Items["A"].Quantity)
+	})
+}
+
+func testVersions(t *testing.T, newRepo func() repository.Cart) {
+	t.Run("updates count up from 1", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		cartID := createCart(t, repo, "user123")
+
+		_, version, err := repo.GetByIDWithVersion(ctx, cartID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), version)
+
+		require.NoError(t, repo.Update(ctx, cartID, newFullCart(t)))
+		got, version, err := repo.GetByIDWithVersion(ctx, cartID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), version)
+		assertSameCart(t, newFullCart(t), got)
+
+		got.Clear()
+		version, err = repo.UpdateIfVersion(ctx, cartID, got, 2)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), version)
+		stored, version, err := repo.GetByIDWithVersion(ctx, cartID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), version)
+		assert.Empty(t, stored.Items)
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		cartID := createCart(t, repo, "user123")
+		require.NoError(t, repo.Update(ctx, cartID, newFullCart(t)))
+
+		// The cart is read at version 2, changed, and refused as version 1
+		stale, _, err := repo.GetByIDWithVersion(ctx, cartID)
+		require.NoError(t, err)
+		stale.Clear()
+		_, err = repo.UpdateIfVersion(ctx, cartID, stale, 1)
+
+		require.ErrorIs(t, err, repository.ErrVersionConflict)
+		var conflict *repository.VersionConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.Equal(t, cartID, conflict.CartID)
+		assert.Equal(t, int64(1), conflict.Expected)
+		assert.Equal(t, int64(2), conflict.Current)
+
+		// The refused change is not stored
+		got, version, err := repo.GetByIDWithVersion(ctx, cartID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), version)
+		assertSameCart(t, newFullCart(t), got)
+	})
+
+	t.Run("by user", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		cartID := createCart(t, repo, "user123")
+		createCart(t, repo, "user456")
+		require.NoError(t, repo.Update(ctx, cartID, newFullCart(t)))
+
+		gotID, got, version, err := repo.GetByUserIDWithVersion(ctx, "user123")
+		require.NoError(t, err)
+		assert.Equal(t, cartID, gotID)
+		assert.Equal(t, int64(2), version)
+		assertSameCart(t, newFullCart(t), got)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		cartID := createCart(t, repo, "user123")
+
+		_, _, _, err := repo.GetByUserIDWithVersion(ctx, "")
+		assert.ErrorIs(t, err, repository.ErrInvalidUserID)
+		_, _, _, err = repo.GetByUserIDWithVersion(ctx, "non_existent")
+		assert.ErrorIs(t, err, repository.ErrCartNotFound)
+
+		_, _, err = repo.GetByIDWithVersion(ctx, "")
+		assert.ErrorIs(t, err, repository.ErrInvalidCartID)
+		_, _, err = repo.GetByIDWithVersion(ctx, "non_existent")
+		assert.ErrorIs(t, err, repository.ErrCartNotFound)
+
+		_, err = repo.UpdateIfVersion(ctx, "", cart.NewCart(), 1)
+		assert.ErrorIs(t, err, repository.ErrInvalidCartID)
+		_, err = repo.UpdateIfVersion(ctx, "non_existent", cart.NewCart(), 1)
+		assert.ErrorIs(t, err, repository.ErrCartNotFound)
+		_, err = repo.UpdateIfVersion(ctx, cartID, nil, 1)
+		assert.Error(t, err)
+		_, err = repo.UpdateIfVersion(ctx, cartID, cart.NewCart(), 0)
+		assert.ErrorIs(t, err, repository.ErrVersionConflict)
+
+		assert.ErrorIs(t, repo.DeleteIfVersion(ctx, "", 1), repository.ErrInvalidCartID)
+		assert.ErrorIs(t, repo.DeleteIfVersion(ctx, "non_existent", 1), repository.ErrCartNotFound)
+	})
+
+	t.Run("delete at a version", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		cartID := createCart(t, repo, "user123")
+		require.NoError(t, repo.Update(ctx, cartID, newFullCart(t)))
+
+		err := repo.DeleteIfVersion(ctx, cartID, 1)
+		var conflict *repository.VersionConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.Equal(t, int64(2), conflict.Current)
+		exists, err := repo.Exists(ctx, cartID)
+		require.NoError(t, err)
+		assert.True(t, exists, "a refused delete keeps the cart")
+
+		require.NoError(t, repo.DeleteIfVersion(ctx, cartID, 2))
+		exists, err = repo.Exists(ctx, cartID)
+		require.NoError(t, err)
+		assert.False(t, exists)
+		// The user can create a cart again
+		createCart(t, repo, "user123")
+	})
+}
+
+func testConcurrency(t *testing.T, newRepo func() repository.Cart) {
+	const numGoroutines = 10
+
+	t.Run("different users", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		results := make(chan error, numGoroutines)
+
+		for i := 0; i < numGoroutines; i++ {
+			go func(id int) {
+				userID := fmt.Sprintf("user%d", id)
+				cartID, err := repo.Create(ctx, userID)
+				if err != nil {
+					results <- err
+					return
+				}
+
+				_, err = repo.GetByID(ctx, cartID)
+				if err != nil {
+					results <- err
+					return
+				}
+
+				updatedCart := cart.NewCart()
+				product := cart.Product{ID: fmt.Sprintf("product%d", id), Price: decimal.NewFromFloat(float64(id * 100))}
+				updatedCart.AddProduct(product, int64(id+1))
+
+				results <- repo.Update(ctx, cartID, updatedCart)
+			}(i)
+		}
+
+		for i := 0; i < numGoroutines; i++ {
+			assert.NoError(t, <-results)
+		}
+		for i := 0; i < numGoroutines; i++ {
+			c, err := repo.GetByUserID(ctx, fmt.Sprintf("user%d", i))
+			require.NoError(t, err)
+			assert.Equal(t, int64(i+1), c.Items[fmt.Sprintf("product%d", i)].Quantity)
+		}
+	})
+
+	t.Run("same user creates one cart", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+
+		var wg sync.WaitGroup
+		cartIDs := make([]string, numGoroutines)
+		errs := make([]error, numGoroutines)
+		for i := 0; i < numGoroutines; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				cartIDs[i], errs[i] = repo.Create(ctx, "user123")
+			}(i)
+		}
+		wg.Wait()
+
+		created := 0
+		for i, err := range errs {
+			if err == nil {
+				created++
+			} else {
+				assert.ErrorIs(t, err, repository.ErrCartExists)
+			}
+			// Everyone gets the cart that won
+			assert.Equal(t, cartIDs[0], cartIDs[i])
+		}
+		assert.Equal(t, 1, created)
+	})
+
+	t.Run("same cart keeps one whole update", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		cartID := createCart(t, repo, "user123")
+
+		var wg sync.WaitGroup
+		errs := make(chan error, numGoroutines)
+		for i := 0; i < numGoroutines; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				updatedCart := cart.NewCart()
+				for line := 0; line <= id; line++ {
+					product := cart.Product{ID: fmt.Sprintf("product%d", line), Price: decimal.NewFromInt(10)}
+					updatedCart.AddProduct(product, int64(id+1))
+				}
+				errs <- repo.Update(ctx, cartID, updatedCart)
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+
+		// The last update wins, lines of different updates are never mixed
+		got, err := repo.GetByID(ctx, cartID)
+		require.NoError(t, err)
+		require.NotEmpty(t, got.Items)
+		for _, item := range got.Items {
+			assert.Equal(t, int64(len(got.Items)), item.Quantity)
+		}
+	})
+
+	t.Run("no update is lost", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		cartID := createCart(t, repo, "user123")
+
+		var wg sync.WaitGroup
+		errs := make(chan error, numGoroutines)
+		for i := 0; i < numGoroutines; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				c, version, err := repo.GetByIDWithVersion(ctx, cartID)
+				if err != nil {
+					errs <- err
+					return
+				}
+				c.AddProduct(cart.Product{ID: fmt.Sprintf("product%d", id), Price: decimal.NewFromInt(10)}, 1)
+				_, err = repo.UpdateIfVersion(ctx, cartID, c, version)
+				errs <- err
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+
+		accepted := 0
+		for err := range errs {
+			if err == nil {
+				accepted++
+			} else {
+				assert.ErrorIs(t, err, repository.ErrVersionConflict)
+			}
+		}
+		assert.GreaterOrEqual(t, accepted, 1)
+
+		// Every accepted update saw the lines of the ones before it
+		got, version, err := repo.GetByIDWithVersion(ctx, cartID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(accepted+1), version)
+		assert.Len(t, got.Items, accepted)
+	})
+}
+
+// createCart creates a cart for the user and returns its ID
+func createCart(t *testing.T, r repository.Cart, userID string) string {
+	t.Helper()
+
+	cartID, err := r.Create(context.Background(), userID)
+	require.NoError(t, err)
+	return cartID
+}
+
+// newFullCart returns a cart that uses every field a repository stores: variant
+// and measured lines, product, bundle and cart-wide promotions, coupons, a tax
+// policy, a strategy and a rounding mode
+func newFullCart(t *testing.T) *cart.Cart {
+	t.Helper()
+
+	c := cart.NewCart()
+	c.Strategy = cart.BestDeal
+	c.Rounding = cart.RoundHalfEven
+	policy, err := cart.DefaultTaxTable().Policy("TH")
+	require.NoError(t, err)
+	c.Tax = &policy
+
+	shirt := cart.Product{
+		ID:       "SHIRT",
+		Price:    decimal.NewFromFloat(500.00),
+		Currency: "THB",
+		Variants: []cart.Variant{{SKU: "SHIRT-M", Options: map[string]string{"size": "M"}}},
+	}
+	medium, err := shirt.Variant("SHIRT-M")
+	require.NoError(t, err)
+	require.NoError(t, c.AddProduct(medium, 2))
+	require.NoError(t, c.AddProduct(cart.Product{ID: "A", Price: decimal.NewFromFloat(100.00), Discount: 10}, 3))
+	require.NoError(t, c.AddMeasure(cart.Product{ID: "COFFEE", Price: decimal.NewFromFloat(800.00), Unit: cart.UnitKilogram}, decimal.NewFromFloat(1.25)))
+
+	c.AddPromotion(cart.Promotion{ID: "A10", ProductID: "A", PromotionType: cart.PercentageDiscount, Discount: 10, Stacking: cart.Stackable})
+	c.AddPromotion(cart.Promotion{ID: "A5", ProductID: "A", PromotionType: cart.FixedAmountOff, Amount: decimal.NewFromFloat(5.00), Stacking: cart.Stackable})
+	c.AddPromotion(cart.Promotion{
+		ID:            "KIT",
+		PromotionType: cart.BundlePrice,
+		Amount:        decimal.NewFromFloat(550.00),
+		BundleItems:   []cart.BundleItem{{ProductID: "A", Quantity: 1}, {ProductID: "SHIRT", Quantity: 1}},
+	})
+	c.AddPromotion(cart.Promotion{
+		ID:            "SUMMER",
+		PromotionType: cart.TotalDiscount,
+		Discount:      5,
+		StartsAt:      time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+		Schedule:      &cart.Schedule{Weekdays: []time.Weekday{time.Saturday}, Location: "Asia/Bangkok"},
+	})
+	c.Coupons["SUMMER5"] = "SUMMER"
+	return c
+}
+
+// assertSameCart compares the stored fields of two carts by their JSON encoding,
+// which does not depend on how decimals happen to be represented. Empty promotion
+// lists compare equal to nil ones.
+func assertSameCart(t *testing.T, want, got *cart.Cart) {
+	t.Helper()
+
+	nilIfEmpty := func(promotions []*cart.Promotion) []*cart.Promotion {
+		if len(promotions) == 0 {
+			return nil
+		}
+		return promotions
+	}
+	encode := func(c *cart.Cart) string {
+		data, err := json.Marshal(map[string]any{
+			"items":          c.Items,
+			"promotion":      c.Promotion,
+			"cartPromotions": nilIfEmpty(c.CartPromotions),
+			"bundles":        nilIfEmpty(c.Bundles),
+			"coupons":        c.Coupons,
+			"strategy":       c.Strategy,
+			"tax":            c.Tax,
+			"currency":       c.Currency,
+			"rounding":       c.Rounding,
+		})
+		require.NoError(t, err)
+		return string(data)
+	}
+	assert.JSONEq(t, encode(want), encode(got))
+}
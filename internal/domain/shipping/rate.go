@@ -0,0 +1,65 @@
+package shipping
+
+import (
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/shopspring/decimal"
+)
+
+type (
+	// FlatRate charges the same amount for any parcel
+	FlatRate struct {
+		Amount cart.Money
+	}
+
+	// WeightBand prices parcels up to and including UpTo kg
+	WeightBand struct {
+		UpTo   decimal.Decimal
+		Amount cart.Money
+	}
+
+	// WeightBandRate charges the first band the billable weight fits in.
+	// Bands must be in ascending UpTo order.
+	WeightBandRate struct {
+		Bands []WeightBand
+		// VolumetricDivisor turns the parcel volume in cm³ into a weight in kg,
+		// e.g. 5000. The heavier of the two is billed. Zero bills the actual weight.
+		VolumetricDivisor decimal.Decimal
+	}
+
+	// PerItemRate charges First for the first unit and Additional for each other unit.
+	// Both must be in the same currency.
+	PerItemRate struct {
+		First      cart.Money
+		Additional cart.Money
+	}
+)
+
+func (r FlatRate) Rate(parcel Parcel) (cart.Money, error) {
+	return r.Amount, nil
+}
+
+func (r WeightBandRate) Rate(parcel Parcel) (cart.Money, error) {
+	weight := r.BillableWeight(parcel)
+	for _, band := range r.Bands {
+		if weight.LessThanOrEqual(band.UpTo) {
+			return band.Amount, nil
+		}
+	}
+	return cart.Money{}, ErrNoRate
+}
+
+// BillableWeight returns the heavier of the actual and the volumetric weight
+func (r WeightBandRate) BillableWeight(parcel Parcel) decimal.Decimal {
+	if !r.VolumetricDivisor.IsPositive() {
+		return parcel.Weight
+	}
+	return decimal.Max(parcel.Weight, parcel.Volume.Div(r.VolumetricDivisor))
+}
+
+func (r PerItemRate) Rate(parcel Parcel) (cart.Money, error) {
+	if parcel.Items <= 0 {
+		return cart.NewMoney(decimal.Zero, r.First.Currency), nil
+	}
+	additional := cart.NewMoney(r.Additional.Amount.Mul(decimal.NewFromInt(parcel.Items-1)), r.Additional.Currency)
+	return r.First.Add(additional)
+}
@@ -0,0 +1,203 @@
+package shipping
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidDestination = errors.New("invalid destination")
+	ErrNoZone             = errors.New("destination is not served")
+	ErrNoRate             = errors.New("no shipping rate for the parcel")
+)
+
+type (
+	// Destination is where a cart is shipped to
+	Destination struct {
+		Country    string // ISO 3166-1 alpha-2 code
+		Region     string
+		PostalCode string
+	}
+
+	// Parcel is what a rate table prices, built from the cart contents
+	Parcel struct {
+		Items  int64
+		Weight decimal.Decimal // kg
+		Volume decimal.Decimal // cm³
+	}
+
+	// RateTable prices a parcel. Implementations must be safe for concurrent use.
+	RateTable interface {
+		Rate(parcel Parcel) (cart.Money, error)
+	}
+
+	// Zone applies a rate table to a set of countries
+	Zone struct {
+		Name      string
+		Countries []string // empty matches every country
+		Table     RateTable
+	}
+
+	// FreeShipping waives the shipping cost once the cart total reaches MinSubtotal.
+	// It only applies to carts in the currency of MinSubtotal.
+	FreeShipping struct {
+		ID          string
+		MinSubtotal cart.Money
+		Countries   []string // empty matches every country
+	}
+
+	// Calculator quotes shipping from the first zone matching the destination
+	Calculator struct {
+		Zones      []Zone
+		Promotions []FreeShipping
+	}
+
+	// Quote is the shipping cost of a cart to a destination, in the cart's currency
+	Quote struct {
+		Zone           string
+		Parcel         Parcel
+		Cost           cart.Money // rate table price
+		Discount       cart.Money // waived by a free-shipping promotion
+		Total          cart.Money
+		FreeShippingID string
+	}
+)
+
+// ParcelOf sums the units, weight and volume of the cart lines.
+// A measured line counts as one item whose measures are per unit of measure.
+func ParcelOf(c *cart.Cart) Parcel {
+	parcel := Parcel{Weight: decimal.Zero, Volume: decimal.Zero}
+	for _, item := range c.Items {
+		qty := decimal.NewFromInt(item.Quantity)
+		parcel.Items += item.Quantity
+		if item.Product.IsMeasured() {
+			qty = item.Measure
+			parcel.Items++
+		}
+		parcel.Weight = parcel.Weight.Add(item.Product.Weight.Mul(qty))
+		parcel.Volume = parcel.Volume.Add(item.Product.Volume().Mul(qty))
+	}
+	return parcel
+}
+
+// Quote prices shipping the cart to the destination. An empty cart ships for free.
+// The zone's rates must be in the cart's currency, otherwise it fails with
+// cart.ErrCurrencyMismatch; a cart without a currency is quoted in the rates' one.
+// Free-shipping promotions are checked against the cart total from CalculateTotal,
+// in the order they were given.
+func (calc Calculator) Quote(c *cart.Cart, dest Destination) (Quote, error) {
+	if err := ValidateDestination(dest); err != nil {
+		return Quote{}, fmt.Errorf("%w: %w", ErrInvalidDestination, err)
+	}
+
+	zone, ok := calc.zoneFor(dest.Country)
+	if !ok {
+		return Quote{}, fmt.Errorf("%w: %s", ErrNoZone, dest.Country)
+	}
+
+	parcel := ParcelOf(c)
+	zero := cart.NewMoney(decimal.Zero, c.Currency)
+	quote := Quote{
+		Zone:     zone.Name,
+		Parcel:   parcel,
+		Cost:     zero,
+		Discount: zero,
+		Total:    zero,
+	}
+	// Rate tables price parcels, there is nothing to ship
+	if parcel.Items == 0 {
+		return quote, nil
+	}
+
+	cost, err := zone.Table.Rate(parcel)
+	if err != nil {
+		return Quote{}, err
+	}
+	if c.Currency != "" && cost.Currency != c.Currency {
+		return Quote{}, fmt.Errorf("%w: cart is in %s, zone %s rates in %s", cart.ErrCurrencyMismatch, c.Currency, zone.Name, cost.Currency)
+	}
+	quote.Cost = cost.Round(c.Rounding)
+	quote.Discount = cart.NewMoney(decimal.Zero, cost.Currency)
+	quote.Total = quote.Cost
+
+	subtotal := c.CalculateTotal()
+	for _, promo := range calc.Promotions {
+		if !matchesCountry(promo.Countries, dest.Country) || promo.MinSubtotal.Currency != cost.Currency {
+			continue
+		}
+		if subtotal.GreaterThanOrEqual(promo.MinSubtotal.Amount) {
+			quote.Discount = quote.Cost
+			quote.Total = cart.NewMoney(decimal.Zero, cost.Currency)
+			quote.FreeShippingID = promo.ID
+			break
+		}
+	}
+
+	return quote, nil
+}
+
+func (calc Calculator) zoneFor(country string) (Zone, bool) {
+	for _, zone := range calc.Zones {
+		if matchesCountry(zone.Countries, country) {
+			return zone, true
+		}
+	}
+	return Zone{}, false
+}
+
+func matchesCountry(countries []string, country string) bool {
+	if len(countries) == 0 {
+		return true
+	}
+	for _, c := range countries {
+		if strings.EqualFold(c, country) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateDestination validates destination data
+func ValidateDestination(dest Destination) error {
+	if strings.TrimSpace(dest.Country) == "" {
+		return errors.New("destination country cannot be empty")
+	}
+
+	return nil
+}
+
+// DefaultCalculator returns the built-in zones, priced in THB: weight bands within
+// Thailand, per-item pricing abroad and free domestic shipping from 1000 THB.
+// Carts in other currencies need a calculator with rates in their currency.
+func DefaultCalculator() Calculator {
+	thb := func(amount int64) cart.Money {
+		return cart.NewMoney(decimal.NewFromInt(amount), "THB")
+	}
+	return Calculator{
+		Zones: []Zone{
+			{
+				Name:      "domestic",
+				Countries: []string{"TH"},
+				Table: WeightBandRate{
+					Bands: []WeightBand{
+						{UpTo: decimal.NewFromInt(1), Amount: thb(40)},
+						{UpTo: decimal.NewFromInt(5), Amount: thb(80)},
+						{UpTo: decimal.NewFromInt(20), Amount: thb(150)},
+					},
+					VolumetricDivisor: decimal.NewFromInt(5000),
+				},
+			},
+			{
+				Name:  "international",
+				Table: PerItemRate{First: thb(450), Additional: thb(150)},
+			},
+		},
+		Promotions: []FreeShipping{
+			{ID: "free-domestic", MinSubtotal: thb(1000), Countries: []string{"TH"}},
+		},
+	}
+}
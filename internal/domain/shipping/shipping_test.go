@@ -0,0 +1,275 @@
+package shipping
+
+import (
+	"testing"
+
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func thb(amount int64) cart.Money {
+	return cart.NewMoney(decimal.NewFromInt(amount), "THB")
+}
+
+func TestRateTables(t *testing.T) {
+	bands := WeightBandRate{
+		Bands: []WeightBand{
+			{UpTo: decimal.NewFromInt(1), Amount: thb(40)},
+			{UpTo: decimal.NewFromInt(5), Amount: thb(80)},
+		},
+	}
+	volumetric := bands
+	volumetric.VolumetricDivisor = decimal.NewFromInt(5000)
+
+	tests := []struct {
+		name    string
+		table   RateTable
+		parcel  Parcel
+		want    cart.Money
+		wantErr error
+	}{
+		{
+			name:   "flat",
+			table:  FlatRate{Amount: thb(50)},
+			parcel: Parcel{Items: 3, Weight: decimal.NewFromInt(10)},
+			want:   thb(50),
+		},
+		{
+			name:   "first weight band",
+			table:  bands,
+			parcel: Parcel{Items: 1, Weight: decimal.NewFromInt(1)},
+			want:   thb(40),
+		},
+		{
+			name:   "second weight band",
+			table:  bands,
+			parcel: Parcel{Items: 1, Weight: decimal.NewFromFloat(1.2)},
+			want:   thb(80),
+		},
+		{
+			name:    "heavier than every band",
+			table:   bands,
+			parcel:  Parcel{Items: 1, Weight: decimal.NewFromInt(6)},
+			wantErr: ErrNoRate,
+		},
+		{
+			name:   "volumetric weight billed when heavier",
+			table:  volumetric,
+			parcel: Parcel{Items: 1, Weight: decimal.NewFromFloat(0.5), Volume: decimal.NewFromInt(10000)}, // 2 kg volumetric
+			want:   thb(80),
+		},
+		{
+			name:   "per item",
+			table:  PerItemRate{First: thb(100), Additional: thb(20)},
+			parcel: Parcel{Items: 4},
+			want:   thb(160),
+		},
+		{
+			name:   "per item empty parcel",
+			table:  PerItemRate{First: thb(100), Additional: thb(20)},
+			parcel: Parcel{},
+			want:   thb(0),
+		},
+		{
+			name:    "per item in two currencies",
+			table:   PerItemRate{First: thb(100), Additional: cart.NewMoney(decimal.NewFromInt(1), "USD")},
+			parcel:  Parcel{Items: 2},
+			wantErr: cart.ErrCurrencyMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.table.Rate(tt.parcel)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.Currency, got.Currency)
+			assert.True(t, tt.want.Amount.Equal(got.Amount), "Expected %s, got %s", tt.want, got)
+		})
+	}
+}
+
+func TestCalculator_Quote(t *testing.T) {
+	tests := []struct {
+		name         string
+		quantity     int64
+		dest         Destination
+		wantZone     string
+		wantCost     decimal.Decimal
+		wantTotal    decimal.Decimal
+		wantFreeShip string
+		wantErr      error
+	}{
+		{
+			name:      "domestic weight band",
+			quantity:  2,
+			dest:      Destination{Country: "TH"},
+			wantZone:  "domestic",
+			wantCost:  decimal.NewFromInt(80), // 3 kg
+			wantTotal: decimal.NewFromInt(80),
+		},
+		{
+			name:         "free domestic shipping from 1000",
+			quantity:     4,
+			dest:         Destination{Country: "th"},
+			wantZone:     "domestic",
+			wantCost:     decimal.NewFromInt(150), // 6 kg
+			wantTotal:    decimal.Zero,
+			wantFreeShip: "free-domestic",
+		},
+		{
+			name:      "international per item",
+			quantity:  4,
+			dest:      Destination{Country: "JP"},
+			wantZone:  "international",
+			wantCost:  decimal.NewFromInt(900),
+			wantTotal: decimal.NewFromInt(900),
+		},
+		{
+			name:     "missing country",
+			quantity: 1,
+			dest:     Destination{},
+			wantErr:  ErrInvalidDestination,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cart.NewCart()
+			product := cart.Product{ID: "A", Price: decimal.NewFromInt(250), Weight: decimal.NewFromFloat(1.5)}
+			require.NoError(t, c.AddProduct(product, tt.quantity))
+
+			got, err := DefaultCalculator().Quote(c, tt.dest)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantZone, got.Zone)
+			assert.Equal(t, tt.quantity, got.Parcel.Items)
+			assert.Equal(t, "THB", got.Total.Currency)
+			assert.True(t, tt.wantCost.Equal(got.Cost.Amount), "cost %s", got.Cost)
+			assert.True(t, tt.wantTotal.Equal(got.Total.Amount), "total %s", got.Total)
+			assert.True(t, got.Cost.Amount.Sub(got.Discount.Amount).Equal(got.Total.Amount))
+			assert.Equal(t, tt.wantFreeShip, got.FreeShippingID)
+		})
+	}
+}
+
+func TestCalculator_Quote_EmptyCart(t *testing.T) {
+	flat := Calculator{Zones: []Zone{{Name: "flat", Table: FlatRate{Amount: thb(50)}}}}
+
+	tests := []struct {
+		name     string
+		calc     Calculator
+		dest     Destination
+		wantZone string
+	}{
+		{name: "domestic weight band", calc: DefaultCalculator(), dest: Destination{Country: "TH"}, wantZone: "domestic"},
+		{name: "international per item", calc: DefaultCalculator(), dest: Destination{Country: "JP"}, wantZone: "international"},
+		{name: "flat rate", calc: flat, dest: Destination{Country: "TH"}, wantZone: "flat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.calc.Quote(cart.NewCart(), tt.dest)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantZone, got.Zone)
+			assert.Equal(t, int64(0), got.Parcel.Items)
+			assert.True(t, got.Cost.Amount.IsZero(), "cost %s", got.Cost)
+			assert.True(t, got.Total.Amount.IsZero(), "total %s", got.Total)
+			assert.Empty(t, got.FreeShippingID)
+		})
+	}
+}
+
+func TestCalculator_Quote_Currency(t *testing.T) {
+	jpy := func(amount int64) cart.Money {
+		return cart.NewMoney(decimal.NewFromInt(amount), "JPY")
+	}
+	yen := Calculator{
+		Zones: []Zone{{Name: "japan", Table: FlatRate{Amount: jpy(800)}}},
+		Promotions: []FreeShipping{
+			{ID: "free-thb", MinSubtotal: thb(1000)},
+			{ID: "free-jpy", MinSubtotal: jpy(10000)},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		calc         Calculator
+		price        int64
+		wantTotal    decimal.Decimal
+		wantFreeShip string
+		wantErr      error
+	}{
+		{
+			name:    "rates in another currency",
+			calc:    DefaultCalculator(),
+			price:   5000,
+			wantErr: cart.ErrCurrencyMismatch,
+		},
+		{
+			name:      "threshold in another currency does not apply",
+			calc:      yen,
+			price:     5000,
+			wantTotal: decimal.NewFromInt(800),
+		},
+		{
+			name:         "threshold in the cart currency",
+			calc:         yen,
+			price:        10000,
+			wantTotal:    decimal.Zero,
+			wantFreeShip: "free-jpy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cart.NewCart()
+			require.NoError(t, c.AddProduct(cart.Product{ID: "A", Price: decimal.NewFromInt(tt.price), Currency: "JPY"}, 1))
+
+			got, err := tt.calc.Quote(c, Destination{Country: "TH"})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "JPY", got.Total.Currency)
+			assert.True(t, tt.wantTotal.Equal(got.Total.Amount), "total %s", got.Total)
+			assert.Equal(t, tt.wantFreeShip, got.FreeShippingID)
+		})
+	}
+}
+
+func TestCalculator_Quote_NoZone(t *testing.T) {
+	calc := Calculator{Zones: []Zone{{Name: "domestic", Countries: []string{"TH"}, Table: FlatRate{Amount: thb(50)}}}}
+
+	_, err := calc.Quote(cart.NewCart(), Destination{Country: "US"})
+	assert.ErrorIs(t, err, ErrNoZone)
+}
+
+func TestParcelOf(t *testing.T) {
+	c := cart.NewCart()
+	require.NoError(t, c.AddProduct(cart.Product{
+		ID:     "A",
+		Weight: decimal.NewFromFloat(0.5),
+		Length: decimal.NewFromInt(10),
+		Width:  decimal.NewFromInt(20),
+		Height: decimal.NewFromInt(5),
+	}, 3))
+	require.NoError(t, c.AddProduct(cart.Product{ID: "B", Weight: decimal.NewFromInt(2)}, 1))
+	// Measured lines are one item weighing Weight per unit of measure
+	require.NoError(t, c.AddMeasure(cart.Product{ID: "COFFEE", Unit: cart.UnitKilogram, Weight: decimal.NewFromInt(1)}, decimal.NewFromFloat(1.25)))
+
+	got := ParcelOf(c)
+
+	assert.Equal(t, int64(5), got.Items)
+	assert.True(t, decimal.NewFromFloat(4.75).Equal(got.Weight))
+	assert.True(t, decimal.NewFromInt(3000).Equal(got.Volume))
+}
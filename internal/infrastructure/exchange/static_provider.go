@@ -0,0 +1,81 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkittipat/try-cart/internal/domain/exchange"
+	"github.com/shopspring/decimal"
+)
+
+var ErrRateNotFound = exchange.ErrRateNotFound
+
+// rateFile is the JSON layout read by NewFileProvider, rates are units of each
+// currency for one unit of Base:
+//
+//	{"base": "THB", "as_of": "2025-06-01T00:00:00Z", "rates": {"USD": "0.0307", "JPY": "4.41"}}
+type rateFile struct {
+	Base  string                     `json:"base"`
+	AsOf  time.Time                  `json:"as_of"`
+	Rates map[string]decimal.Decimal `json:"rates"`
+}
+
+type staticProvider struct {
+	base  string
+	asOf  time.Time
+	rates map[string]decimal.Decimal // currency -> units per one base unit
+}
+
+// NewStaticProvider serves fixed rates against a base currency. Rates between two
+// other currencies are crossed through the base.
+func NewStaticProvider(base string, asOf time.Time, rates map[string]decimal.Decimal) (exchange.ExchangeRateProvider, error) {
+	p := &staticProvider{
+		base:  strings.ToUpper(base),
+		asOf:  asOf,
+		rates: make(map[string]decimal.Decimal, len(rates)),
+	}
+
+	for currency, rate := range rates {
+		currency = strings.ToUpper(currency)
+		if err := exchange.ValidateRate(exchange.Rate{From: p.base, To: currency, Rate: rate}); err != nil {
+			return nil, err
+		}
+		p.rates[currency] = rate
+	}
+	p.rates[p.base] = decimal.NewFromInt(1)
+
+	return p, nil
+}
+
+// NewFileProvider loads a static provider from a JSON rate file
+func NewFileProvider(path string) (exchange.ExchangeRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file rateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse rate file %s: %w", path, err)
+	}
+
+	return NewStaticProvider(file.Base, file.AsOf, file.Rates)
+}
+
+func (p *staticProvider) Rate(ctx context.Context, from, to string) (exchange.Rate, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	fromRate, ok := p.rates[from]
+	if !ok {
+		return exchange.Rate{}, fmt.Errorf("%w: %s", ErrRateNotFound, from)
+	}
+	toRate, ok := p.rates[to]
+	if !ok {
+		return exchange.Rate{}, fmt.Errorf("%w: %s", ErrRateNotFound, to)
+	}
+
+	return exchange.Rate{From: from, To: to, Rate: toRate.Div(fromRate), AsOf: p.asOf}, nil
+}
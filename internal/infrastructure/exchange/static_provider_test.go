@@ -0,0 +1,72 @@
+package exchange
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticProvider_Rate(t *testing.T) {
+	asOf := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	provider, err := NewStaticProvider("thb", asOf, map[string]decimal.Decimal{
+		"USD": decimal.RequireFromString("0.03"),
+		"JPY": decimal.RequireFromString("4.5"),
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+		wantErr  error
+	}{
+		{name: "from base", from: "THB", to: "USD", want: "0.03"},
+		{name: "to base", from: "USD", to: "THB", want: "33.3333333333333333"},
+		{name: "cross rate", from: "usd", to: "jpy", want: "150"},
+		{name: "same currency", from: "THB", to: "THB", want: "1"},
+		{name: "unknown currency", from: "THB", to: "EUR", wantErr: ErrRateNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := provider.Rate(context.Background(), tt.from, tt.to)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, decimal.RequireFromString(tt.want).Equal(got.Rate), "Expected %s, got %s", tt.want, got.Rate)
+			assert.Equal(t, asOf, got.AsOf)
+		})
+	}
+}
+
+func TestNewStaticProvider_InvalidRate(t *testing.T) {
+	_, err := NewStaticProvider("THB", time.Time{}, map[string]decimal.Decimal{"USD": decimal.Zero})
+	assert.EqualError(t, err, "exchange rate must be positive")
+
+	_, err = NewStaticProvider("THB", time.Time{}, map[string]decimal.Decimal{"XXX": decimal.NewFromInt(1)})
+	assert.Error(t, err)
+}
+
+func TestNewFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"base":"THB","as_of":"2025-06-01T00:00:00Z","rates":{"USD":"0.03"}}`), 0o600))
+
+	provider, err := NewFileProvider(path)
+	require.NoError(t, err)
+
+	got, err := provider.Rate(context.Background(), "THB", "USD")
+	require.NoError(t, err)
+	assert.True(t, decimal.RequireFromString("0.03").Equal(got.Rate))
+	assert.Equal(t, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), got.AsOf)
+
+	_, err = NewFileProvider(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
@@ -11,21 +11,28 @@ import (
 	"github.com/pkittipat/try-cart/internal/domain/repository"
 )
 
+// Errors are shared with the domain contract so callers can match them
+// without depending on a specific implementation.
 var (
-	ErrCartNotFound    = errors.New("cart not found")
-	ErrCartExists      = errors.New("cart already exists")
-	ErrInvalidCartID   = errors.New("invalid cart ID")
-	ErrInvalidUserID   = errors.New("invalid user ID")
+	ErrCartNotFound  = repository.ErrCartNotFound
+	ErrCartExists    = repository.ErrCartExists
+	ErrInvalidCartID = repository.ErrInvalidCartID
+	ErrInvalidUserID = repository.ErrInvalidUserID
+
+	ErrVersionConflict = repository.ErrVersionConflict
 )
 
 type CartData struct {
 	ID        string
 	UserID    string
 	Cart      *cart.Cart
+	Version   int64 // 1 when created, incremented by every update
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+// cartRepository keeps carts in memory. It stores and returns copies, so callers
+// can change a cart they read without a lock and only Update makes it visible.
 type cartRepository struct {
 	mu    sync.RWMutex
 	carts map[string]*CartData
@@ -60,6 +67,7 @@ func (r *cartRepository) Create(ctx context.Context, userID string) (string, err
 		ID:        cartID,
 		UserID:    userID,
 		Cart:      cart.NewCart(),
+		Version:   1,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
@@ -83,12 +91,49 @@ func (r *cartRepository) GetByID(ctx context.Context, cartID string) (*cart.Cart
 		return nil, ErrCartNotFound
 	}
 
-	return cartData.Cart, nil
+	return cartData.Cart.Clone(), nil
+}
+
+func (r *cartRepository) GetOwnerID(ctx context.Context, cartID string) (string, error) {
+	if cartID == "" {
+		return "", ErrInvalidCartID
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cartData, exists := r.carts[cartID]
+	if !exists {
+		return "", ErrCartNotFound
+	}
+
+	return cartData.UserID, nil
+}
+
+func (r *cartRepository) GetByIDWithVersion(ctx context.Context, cartID string) (*cart.Cart, int64, error) {
+	if cartID == "" {
+		return nil, 0, ErrInvalidCartID
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cartData, exists := r.carts[cartID]
+	if !exists {
+		return nil, 0, ErrCartNotFound
+	}
+
+	return cartData.Cart.Clone(), cartData.Version, nil
 }
 
 func (r *cartRepository) GetByUserID(ctx context.Context, userID string) (*cart.Cart, error) {
+	_, c, _, err := r.GetByUserIDWithVersion(ctx, userID)
+	return c, err
+}
+
+func (r *cartRepository) GetByUserIDWithVersion(ctx context.Context, userID string) (string, *cart.Cart, int64, error) {
 	if userID == "" {
-		return nil, ErrInvalidUserID
+		return "", nil, 0, ErrInvalidUserID
 	}
 
 	r.mu.RLock()
@@ -96,15 +141,15 @@ func (r *cartRepository) GetByUserID(ctx context.Context, userID string) (*cart.
 
 	cartID, exists := r.userCarts[userID]
 	if !exists {
-		return nil, ErrCartNotFound
+		return "", nil, 0, ErrCartNotFound
 	}
 
 	cartData, exists := r.carts[cartID]
 	if !exists {
-		return nil, ErrCartNotFound
+		return "", nil, 0, ErrCartNotFound
 	}
 
-	return cartData.Cart, nil
+	return cartID, cartData.Cart.Clone(), cartData.Version, nil
 }
 
 func (r *cartRepository) Update(ctx context.Context, cartID string, updatedCart *cart.Cart) error {
@@ -123,12 +168,39 @@ func (r *cartRepository) Update(ctx context.Context, cartID string, updatedCart
 		return ErrCartNotFound
 	}
 
-	cartData.Cart = updatedCart
+	cartData.Cart = updatedCart.Clone()
+	cartData.Version++
 	cartData.UpdatedAt = time.Now()
 
 	return nil
 }
 
+func (r *cartRepository) UpdateIfVersion(ctx context.Context, cartID string, updatedCart *cart.Cart, expectedVersion int64) (int64, error) {
+	if cartID == "" {
+		return 0, ErrInvalidCartID
+	}
+	if updatedCart == nil {
+		return 0, errors.New("cart cannot be nil")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cartData, exists := r.carts[cartID]
+	if !exists {
+		return 0, ErrCartNotFound
+	}
+	if cartData.Version != expectedVersion {
+		return 0, &repository.VersionConflictError{CartID: cartID, Expected: expectedVersion, Current: cartData.Version}
+	}
+
+	cartData.Cart = updatedCart.Clone()
+	cartData.Version++
+	cartData.UpdatedAt = time.Now()
+
+	return cartData.Version, nil
+}
+
 func (r *cartRepository) Delete(ctx context.Context, cartID string) error {
 	if cartID == "" {
 		return ErrInvalidCartID
@@ -148,6 +220,28 @@ func (r *cartRepository) Delete(ctx context.Context, cartID string) error {
 	return nil
 }
 
+func (r *cartRepository) DeleteIfVersion(ctx context.Context, cartID string, expectedVersion int64) error {
+	if cartID == "" {
+		return ErrInvalidCartID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cartData, exists := r.carts[cartID]
+	if !exists {
+		return ErrCartNotFound
+	}
+	if cartData.Version != expectedVersion {
+		return &repository.VersionConflictError{CartID: cartID, Expected: expectedVersion, Current: cartData.Version}
+	}
+
+	delete(r.carts, cartID)
+	delete(r.userCarts, cartData.UserID)
+
+	return nil
+}
+
 func (r *cartRepository) Exists(ctx context.Context, cartID string) (bool, error) {
 	if cartID == "" {
 		return false, ErrInvalidCartID
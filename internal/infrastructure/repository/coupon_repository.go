@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/pkittipat/try-cart/internal/domain/repository"
+)
+
+var (
+	ErrCouponNotFound = repository.ErrCouponNotFound
+	ErrCouponExists   = repository.ErrCouponExists
+)
+
+type couponData struct {
+	coupon      cart.Coupon
+	redemptions int64
+	byUser      map[string]int64 // userID -> redemptions
+}
+
+type couponRepository struct {
+	mu      sync.RWMutex
+	coupons map[string]*couponData
+}
+
+func NewCouponRepository() repository.Coupon {
+	return &couponRepository{
+		coupons: make(map[string]*couponData),
+	}
+}
+
+func (r *couponRepository) Create(ctx context.Context, coupon cart.Coupon) error {
+	code := cart.NormalizeCouponCode(coupon.Code)
+	coupon.Code = code
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.coupons[code]; exists {
+		return ErrCouponExists
+	}
+
+	r.coupons[code] = &couponData{
+		coupon: coupon,
+		byUser: make(map[string]int64),
+	}
+	return nil
+}
+
+func (r *couponRepository) GetByCode(ctx context.Context, code string) (cart.Coupon, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	data, exists := r.coupons[cart.NormalizeCouponCode(code)]
+	if !exists {
+		return cart.Coupon{}, ErrCouponNotFound
+	}
+
+	return data.coupon, nil
+}
+
+// Redeem checks the usage limits and counts the redemption under one lock,
+// so concurrent redemptions cannot exceed a limit
+func (r *couponRepository) Redeem(ctx context.Context, code string, userID string) error {
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, exists := r.coupons[cart.NormalizeCouponCode(code)]
+	if !exists {
+		return ErrCouponNotFound
+	}
+
+	if err := data.coupon.CheckRedemption(data.redemptions, data.byUser[userID]); err != nil {
+		return err
+	}
+
+	data.redemptions++
+	data.byUser[userID]++
+	return nil
+}
+
+func (r *couponRepository) CancelRedemption(ctx context.Context, code string, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, exists := r.coupons[cart.NormalizeCouponCode(code)]
+	if !exists {
+		return ErrCouponNotFound
+	}
+
+	if data.byUser[userID] > 0 {
+		data.redemptions--
+		data.byUser[userID]--
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCouponRepository_CreateAndGet(t *testing.T) {
+	repo := NewCouponRepository()
+	ctx := context.Background()
+	coupon := cart.Coupon{Code: " save10 ", Promotion: cart.Promotion{PromotionType: cart.TotalDiscount, Discount: 10}}
+
+	require.NoError(t, repo.Create(ctx, coupon))
+	assert.Equal(t, ErrCouponExists, repo.Create(ctx, cart.Coupon{Code: "SAVE10"}))
+
+	got, err := repo.GetByCode(ctx, "Save10")
+	require.NoError(t, err)
+	assert.Equal(t, "SAVE10", got.Code)
+	assert.Equal(t, cart.TotalDiscount, got.Promotion.PromotionType)
+
+	_, err = repo.GetByCode(ctx, "unknown")
+	assert.Equal(t, ErrCouponNotFound, err)
+}
+
+func TestCouponRepository_Redeem(t *testing.T) {
+	tests := []struct {
+		name    string
+		coupon  cart.Coupon
+		redeems []string // user IDs redeeming in order
+		wantErr []bool
+	}{
+		{
+			name:    "per user limit",
+			coupon:  cart.Coupon{Code: "A", MaxRedemptionsPerUser: 1},
+			redeems: []string{"u1", "u2", "u1"},
+			wantErr: []bool{false, false, true},
+		},
+		{
+			name:    "total limit",
+			coupon:  cart.Coupon{Code: "A", MaxRedemptions: 2},
+			redeems: []string{"u1", "u1", "u2"},
+			wantErr: []bool{false, false, true},
+		},
+		{
+			name:    "single use",
+			coupon:  cart.Coupon{Code: "A", SingleUse: true},
+			redeems: []string{"u1", "u2"},
+			wantErr: []bool{false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewCouponRepository()
+			ctx := context.Background()
+			require.NoError(t, repo.Create(ctx, tt.coupon))
+
+			for i, userID := range tt.redeems {
+				err := repo.Redeem(ctx, "a", userID)
+				if tt.wantErr[i] {
+					assert.ErrorIs(t, err, cart.ErrCouponRejected)
+				} else {
+					assert.NoError(t, err)
+				}
+			}
+		})
+	}
+
+	repo := NewCouponRepository()
+	assert.Equal(t, ErrCouponNotFound, repo.Redeem(context.Background(), "missing", "u1"))
+	assert.Equal(t, ErrInvalidUserID, repo.Redeem(context.Background(), "missing", ""))
+}
+
+func TestCouponRepository_CancelRedemption(t *testing.T) {
+	repo := NewCouponRepository()
+	ctx := context.Background()
+	require.NoError(t, repo.Create(ctx, cart.Coupon{Code: "A", MaxRedemptions: 1, MaxRedemptionsPerUser: 1}))
+
+	require.NoError(t, repo.Redeem(ctx, "A", "u1"))
+	require.Error(t, repo.Redeem(ctx, "A", "u2"))
+
+	require.NoError(t, repo.CancelRedemption(ctx, "a", "u1"))
+	// A user without redemptions has nothing to take back
+	require.NoError(t, repo.CancelRedemption(ctx, "A", "u2"))
+	require.NoError(t, repo.Redeem(ctx, "A", "u2"))
+	assert.Error(t, repo.Redeem(ctx, "A", "u1"))
+
+	assert.Equal(t, ErrCouponNotFound, repo.CancelRedemption(ctx, "unknown", "u1"))
+}
+
+func TestCouponRepository_Redeem_ThreadSafety(t *testing.T) {
+	repo := NewCouponRepository()
+	ctx := context.Background()
+	require.NoError(t, repo.Create(ctx, cart.Coupon{Code: "LIMITED", MaxRedemptions: 5}))
+
+	const numGoroutines = 20
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		redeemed int
+	)
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if err := repo.Redeem(ctx, "LIMITED", fmt.Sprintf("user%d", id)); err == nil {
+				mu.Lock()
+				redeemed++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, redeemed)
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrations embed.FS
+
+// migrationLockID serializes servers that migrate the same database at startup
+const migrationLockID = 7_305_417_101
+
+// MigratePostgres applies the schema migrations db has not run yet in file name order.
+// Each migration runs in its own transaction and is recorded in schema_migrations.
+func MigratePostgres(ctx context.Context, db *sql.DB) error {
+	lock := func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID)
+		return err
+	}
+	return migrate(ctx, db, "migrations/postgres", lock)
+}
+
+// MigrateSQLite applies the SQLite schema migrations like MigratePostgres
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	lock := func(ctx context.Context, tx *sql.Tx) error {
+		// Writing before reading takes the database's write lock, so a concurrent
+		// migration waits instead of failing on a stale read
+		_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE false`)
+		return err
+	}
+	return migrate(ctx, db, "migrations/sqlite", lock)
+}
+
+// migrate applies the migrations in dir. lock runs first in each migration's transaction.
+func migrate(ctx context.Context, db *sql.DB, dir string, lock func(context.Context, *sql.Tx) error) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	names, err := fs.Glob(migrations, dir+"/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := applyMigration(ctx, db, name, lock); err != nil {
+			return fmt.Errorf("migration %s: %w", path.Base(name), err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, name string, lock func(context.Context, *sql.Tx) error) error {
+	script, err := migrations.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	version := strings.TrimSuffix(path.Base(name), ".sql")
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lock(ctx, tx); err != nil {
+		return err
+	}
+
+	var applied bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&applied); err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,33 @@
+-- Carts with their lines and promotions. Products, promotions and the tax policy
+-- are stored as JSON snapshots taken when they were added to the cart.
+CREATE TABLE carts (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL UNIQUE,
+    currency   TEXT NOT NULL DEFAULT '',
+    strategy   TEXT NOT NULL DEFAULT '',
+    rounding   TEXT NOT NULL DEFAULT '',
+    tax        JSONB,
+    coupons    JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE cart_items (
+    cart_id    TEXT NOT NULL REFERENCES carts (id) ON DELETE CASCADE,
+    sku        TEXT NOT NULL,
+    product_id TEXT NOT NULL,
+    product    JSONB NOT NULL,
+    quantity   BIGINT NOT NULL DEFAULT 0,
+    measure    NUMERIC NOT NULL DEFAULT 0,
+    PRIMARY KEY (cart_id, sku)
+);
+
+-- scope is product, bundle or cart. position keeps the order promotions were added in.
+CREATE TABLE cart_promotions (
+    cart_id    TEXT NOT NULL REFERENCES carts (id) ON DELETE CASCADE,
+    position   INTEGER NOT NULL,
+    scope      TEXT NOT NULL,
+    product_id TEXT NOT NULL DEFAULT '',
+    promotion  JSONB NOT NULL,
+    PRIMARY KEY (cart_id, position)
+);
@@ -0,0 +1,2 @@
+-- version starts at 1 and grows with every update, for optimistic concurrency
+ALTER TABLE carts ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
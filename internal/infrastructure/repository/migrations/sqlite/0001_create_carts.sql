@@ -0,0 +1,33 @@
+-- Carts with their lines and promotions, see the PostgreSQL schema. JSON columns
+-- are TEXT, and measure is TEXT so decimal amounts are stored exactly.
+CREATE TABLE carts (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL UNIQUE,
+    currency   TEXT NOT NULL DEFAULT '',
+    strategy   TEXT NOT NULL DEFAULT '',
+    rounding   TEXT NOT NULL DEFAULT '',
+    tax        TEXT,
+    coupons    TEXT NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE cart_items (
+    cart_id    TEXT NOT NULL REFERENCES carts (id) ON DELETE CASCADE,
+    sku        TEXT NOT NULL,
+    product_id TEXT NOT NULL,
+    product    TEXT NOT NULL,
+    quantity   INTEGER NOT NULL DEFAULT 0,
+    measure    TEXT NOT NULL DEFAULT '0',
+    PRIMARY KEY (cart_id, sku)
+);
+
+-- scope is product, bundle or cart. position keeps the order promotions were added in.
+CREATE TABLE cart_promotions (
+    cart_id    TEXT NOT NULL REFERENCES carts (id) ON DELETE CASCADE,
+    position   INTEGER NOT NULL,
+    scope      TEXT NOT NULL,
+    product_id TEXT NOT NULL DEFAULT '',
+    promotion  TEXT NOT NULL,
+    PRIMARY KEY (cart_id, position)
+);
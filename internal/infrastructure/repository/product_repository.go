@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/pkittipat/try-cart/internal/domain/catalog"
+)
+
+var (
+	ErrProductNotFound = catalog.ErrProductNotFound
+	ErrProductExists   = catalog.ErrProductExists
+)
+
+type productRepository struct {
+	mu       sync.RWMutex
+	products map[string]cart.Product
+}
+
+func NewProductRepository() catalog.Product {
+	return &productRepository{
+		products: make(map[string]cart.Product),
+	}
+}
+
+func (r *productRepository) Create(ctx context.Context, product cart.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.products[product.ID]; exists {
+		return ErrProductExists
+	}
+
+	r.products[product.ID] = copyProduct(product)
+	return nil
+}
+
+func (r *productRepository) GetByID(ctx context.Context, productID string) (cart.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	product, exists := r.products[productID]
+	if !exists {
+		return cart.Product{}, ErrProductNotFound
+	}
+
+	return copyProduct(product), nil
+}
+
+func (r *productRepository) List(ctx context.Context) ([]cart.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	products := make([]cart.Product, 0, len(r.products))
+	for _, product := range r.products {
+		products = append(products, copyProduct(product))
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].ID < products[j].ID
+	})
+
+	return products, nil
+}
+
+func (r *productRepository) Update(ctx context.Context, product cart.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.products[product.ID]; !exists {
+		return ErrProductNotFound
+	}
+
+	r.products[product.ID] = copyProduct(product)
+	return nil
+}
+
+func (r *productRepository) Delete(ctx context.Context, productID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.products[productID]; !exists {
+		return ErrProductNotFound
+	}
+
+	delete(r.products, productID)
+	return nil
+}
+
+// copyProduct keeps callers from changing stored tiers and variants through shared slices
+func copyProduct(product cart.Product) cart.Product {
+	product.PriceTiers = slices.Clone(product.PriceTiers)
+	product.Variants = slices.Clone(product.Variants)
+	for i, variant := range product.Variants {
+		product.Variants[i].Options = maps.Clone(variant.Options)
+	}
+	return product
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductRepository_CRUD(t *testing.T) {
+	repo := NewProductRepository()
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, cart.Product{ID: "B", Price: decimal.NewFromFloat(50.00)}))
+	require.NoError(t, repo.Create(ctx, cart.Product{ID: "A", Price: decimal.NewFromFloat(100.00), Discount: 10}))
+	assert.Equal(t, ErrProductExists, repo.Create(ctx, cart.Product{ID: "A"}))
+
+	got, err := repo.GetByID(ctx, "A")
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromFloat(100.00).Equal(got.Price))
+	assert.Equal(t, int64(10), got.Discount)
+
+	_, err = repo.GetByID(ctx, "Z")
+	assert.Equal(t, ErrProductNotFound, err)
+
+	require.NoError(t, repo.Update(ctx, cart.Product{ID: "A", Price: decimal.NewFromFloat(80.00)}))
+	assert.Equal(t, ErrProductNotFound, repo.Update(ctx, cart.Product{ID: "Z"}))
+	got, err = repo.GetByID(ctx, "A")
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromFloat(80.00).Equal(got.Price))
+
+	products, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, products, 2)
+	assert.Equal(t, "A", products[0].ID)
+	assert.Equal(t, "B", products[1].ID)
+
+	require.NoError(t, repo.Delete(ctx, "A"))
+	assert.Equal(t, ErrProductNotFound, repo.Delete(ctx, "A"))
+	_, err = repo.GetByID(ctx, "A")
+	assert.Equal(t, ErrProductNotFound, err)
+}
+
+func TestProductRepository_CopiesPriceTiers(t *testing.T) {
+	repo := NewProductRepository()
+	ctx := context.Background()
+
+	tiers := []cart.PriceTier{{MinQuantity: 10, UnitPrice: decimal.NewFromFloat(90.00)}}
+	require.NoError(t, repo.Create(ctx, cart.Product{ID: "A", Price: decimal.NewFromFloat(100.00), PriceTiers: tiers}))
+	tiers[0].UnitPrice = decimal.Zero
+
+	got, err := repo.GetByID(ctx, "A")
+	require.NoError(t, err)
+	got.PriceTiers[0].UnitPrice = decimal.Zero
+
+	got, err = repo.GetByID(ctx, "A")
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromFloat(90.00).Equal(got.PriceTiers[0].UnitPrice))
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"github.com/pkittipat/try-cart/internal/domain/repository"
+)
+
+type purchaseHistoryRepository struct {
+	mu        sync.RWMutex
+	purchases map[string]map[string]int64 // userID -> product ID -> units
+}
+
+func NewPurchaseHistoryRepository() repository.PurchaseHistory {
+	return &purchaseHistoryRepository{
+		purchases: make(map[string]map[string]int64),
+	}
+}
+
+func (r *purchaseHistoryRepository) Purchased(ctx context.Context, userID string) (map[string]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return maps.Clone(r.purchases[userID]), nil
+}
+
+func (r *purchaseHistoryRepository) Record(ctx context.Context, userID string, units map[string]int64) error {
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purchased, exists := r.purchases[userID]
+	if !exists {
+		purchased = make(map[string]int64, len(units))
+		r.purchases[userID] = purchased
+	}
+	for productID, quantity := range units {
+		purchased[productID] += quantity
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurchaseHistoryRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewPurchaseHistoryRepository()
+
+	purchased, err := repo.Purchased(ctx, "user1")
+	require.NoError(t, err)
+	assert.Empty(t, purchased)
+
+	require.NoError(t, repo.Record(ctx, "user1", map[string]int64{"LTD": 1, "A": 2}))
+	require.NoError(t, repo.Record(ctx, "user1", map[string]int64{"LTD": 1}))
+	assert.ErrorIs(t, repo.Record(ctx, "", map[string]int64{"LTD": 1}), ErrInvalidUserID)
+
+	purchased, err = repo.Purchased(ctx, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"LTD": 2, "A": 2}, purchased)
+
+	// The map returned is the caller's copy
+	purchased["LTD"] = 10
+	purchased, err = repo.Purchased(ctx, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), purchased["LTD"])
+
+	purchased, err = repo.Purchased(ctx, "user2")
+	require.NoError(t, err)
+	assert.Empty(t, purchased)
+}
@@ -0,0 +1,423 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/pkittipat/try-cart/internal/domain/repository"
+)
+
+// Promotion scopes in the cart_promotions table
+const (
+	scopeProduct = "product"
+	scopeBundle  = "bundle"
+	scopeCart    = "cart"
+)
+
+// sqlCartRepository stores carts in PostgreSQL or SQLite. Its queries use the SQL
+// both understand, and JSON columns are written as text. Every Update rewrites the
+// cart's lines and promotions in one transaction. Limits, Clock and Purchased are
+// runtime settings of a cart and are not stored.
+type sqlCartRepository struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// NewPostgresCartRepository returns a cart repository backed by db, opened with the
+// pgx driver. The schema must be up to date, see MigratePostgres.
+func NewPostgresCartRepository(db *sql.DB) repository.Cart {
+	return &sqlCartRepository{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+func (r *sqlCartRepository) Create(ctx context.Context, userID string) (string, error) {
+	if userID == "" {
+		return "", ErrInvalidUserID
+	}
+
+	now := r.now()
+	cartID := fmt.Sprintf("cart_%s_%d", userID, now.UnixNano())
+
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO carts (id, user_id, created_at, updated_at) VALUES ($1, $2, $3, $3)
+		ON CONFLICT (user_id) DO NOTHING
+		RETURNING id`, cartID, userID, now).Scan(&cartID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The user already has a cart
+		if err := r.db.QueryRowContext(ctx, `SELECT id FROM carts WHERE user_id = $1`, userID).Scan(&cartID); err != nil {
+			return "", fmt.Errorf("get existing cart: %w", err)
+		}
+		return cartID, ErrCartExists
+	}
+	if err != nil {
+		return "", fmt.Errorf("create cart: %w", err)
+	}
+
+	return cartID, nil
+}
+
+func (r *sqlCartRepository) GetByID(ctx context.Context, cartID string) (*cart.Cart, error) {
+	c, _, err := r.GetByIDWithVersion(ctx, cartID)
+	return c, err
+}
+
+func (r *sqlCartRepository) GetOwnerID(ctx context.Context, cartID string) (string, error) {
+	if cartID == "" {
+		return "", ErrInvalidCartID
+	}
+
+	var userID string
+	err := r.db.QueryRowContext(ctx, `SELECT user_id FROM carts WHERE id = $1`, cartID).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrCartNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("get cart owner: %w", err)
+	}
+	return userID, nil
+}
+
+func (r *sqlCartRepository) GetByIDWithVersion(ctx context.Context, cartID string) (*cart.Cart, int64, error) {
+	if cartID == "" {
+		return nil, 0, ErrInvalidCartID
+	}
+
+	_, c, version, err := r.load(ctx, `SELECT id, version, currency, strategy, rounding, tax, coupons FROM carts WHERE id = $1`, cartID)
+	return c, version, err
+}
+
+func (r *sqlCartRepository) GetByUserID(ctx context.Context, userID string) (*cart.Cart, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+
+	_, c, _, err := r.GetByUserIDWithVersion(ctx, userID)
+	return c, err
+}
+
+func (r *sqlCartRepository) GetByUserIDWithVersion(ctx context.Context, userID string) (string, *cart.Cart, int64, error) {
+	if userID == "" {
+		return "", nil, 0, ErrInvalidUserID
+	}
+
+	return r.load(ctx, `SELECT id, version, currency, strategy, rounding, tax, coupons FROM carts WHERE user_id = $1`, userID)
+}
+
+func (r *sqlCartRepository) Update(ctx context.Context, cartID string, updatedCart *cart.Cart) error {
+	_, err := r.write(ctx, cartID, updatedCart, nil)
+	return err
+}
+
+func (r *sqlCartRepository) UpdateIfVersion(ctx context.Context, cartID string, updatedCart *cart.Cart, expectedVersion int64) (int64, error) {
+	return r.write(ctx, cartID, updatedCart, &expectedVersion)
+}
+
+// write stores the cart and returns its new version. When expectedVersion is set
+// the cart must still be at that version.
+func (r *sqlCartRepository) write(ctx context.Context, cartID string, updatedCart *cart.Cart, expectedVersion *int64) (int64, error) {
+	if cartID == "" {
+		return 0, ErrInvalidCartID
+	}
+	if updatedCart == nil {
+		return 0, errors.New("cart cannot be nil")
+	}
+
+	var tax sql.NullString
+	if updatedCart.Tax != nil {
+		data, err := json.Marshal(updatedCart.Tax)
+		if err != nil {
+			return 0, fmt.Errorf("encode tax policy: %w", err)
+		}
+		tax = sql.NullString{String: string(data), Valid: true}
+	}
+	coupons, err := json.Marshal(nonNilMap(updatedCart.Coupons))
+	if err != nil {
+		return 0, fmt.Errorf("encode coupons: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE carts SET currency = $2, strategy = $3, rounding = $4, tax = $5, coupons = $6, updated_at = $7,
+			version = version + 1
+		WHERE id = $1`
+	args := []any{cartID, updatedCart.Currency, string(updatedCart.Strategy), string(updatedCart.Rounding), tax, string(coupons), r.now()}
+	if expectedVersion != nil {
+		query += ` AND version = $8`
+		args = append(args, *expectedVersion)
+	}
+
+	var version int64
+	err = tx.QueryRowContext(ctx, query+` RETURNING version`, args...).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, missedUpdate(ctx, tx, cartID, expectedVersion)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("update cart: %w", err)
+	}
+
+	if err := replaceItems(ctx, tx, cartID, updatedCart.Items); err != nil {
+		return 0, err
+	}
+	if err := replacePromotions(ctx, tx, cartID, updatedCart); err != nil {
+		return 0, err
+	}
+
+	return version, tx.Commit()
+}
+
+// missedUpdate tells why an update changed no row: the cart is gone or it is at another version
+func missedUpdate(ctx context.Context, tx *sql.Tx, cartID string, expectedVersion *int64) error {
+	var current int64
+	err := tx.QueryRowContext(ctx, `SELECT version FROM carts WHERE id = $1`, cartID).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && expectedVersion == nil) {
+		return ErrCartNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("get cart version: %w", err)
+	}
+
+	return &repository.VersionConflictError{CartID: cartID, Expected: *expectedVersion, Current: current}
+}
+
+func (r *sqlCartRepository) Delete(ctx context.Context, cartID string) error {
+	return r.delete(ctx, cartID, nil)
+}
+
+func (r *sqlCartRepository) DeleteIfVersion(ctx context.Context, cartID string, expectedVersion int64) error {
+	return r.delete(ctx, cartID, &expectedVersion)
+}
+
+// delete removes the cart. When expectedVersion is set the cart must still be at
+// that version.
+func (r *sqlCartRepository) delete(ctx context.Context, cartID string, expectedVersion *int64) error {
+	if cartID == "" {
+		return ErrInvalidCartID
+	}
+
+	query, args := `DELETE FROM carts WHERE id = $1`, []any{cartID}
+	if expectedVersion != nil {
+		query += ` AND version = $2`
+		args = append(args, *expectedVersion)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lines and promotions are deleted with the cart
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("delete cart: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return missedUpdate(ctx, tx, cartID, expectedVersion)
+	}
+
+	return tx.Commit()
+}
+
+func (r *sqlCartRepository) Exists(ctx context.Context, cartID string) (bool, error) {
+	if cartID == "" {
+		return false, ErrInvalidCartID
+	}
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM carts WHERE id = $1)`, cartID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("check cart: %w", err)
+	}
+	return exists, nil
+}
+
+// load reads the cart selected by query with its version, lines and promotions from one snapshot
+func (r *sqlCartRepository) load(ctx context.Context, query string, arg string) (string, *cart.Cart, int64, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return "", nil, 0, err
+	}
+	defer tx.Rollback()
+
+	var (
+		cartID, currency, strategy, rounding string
+		version                              int64
+		tax, coupons                         []byte
+	)
+	err = tx.QueryRowContext(ctx, query, arg).Scan(&cartID, &version, &currency, &strategy, &rounding, &tax, &coupons)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, 0, ErrCartNotFound
+	}
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("get cart: %w", err)
+	}
+
+	c := cart.NewCart()
+	c.Currency = currency
+	c.Strategy = cart.PromotionStrategy(strategy)
+	c.Rounding = cart.RoundingMode(rounding)
+	if tax != nil {
+		if err := json.Unmarshal(tax, &c.Tax); err != nil {
+			return "", nil, 0, fmt.Errorf("decode tax policy: %w", err)
+		}
+	}
+	if err := json.Unmarshal(coupons, &c.Coupons); err != nil {
+		return "", nil, 0, fmt.Errorf("decode coupons: %w", err)
+	}
+
+	if err := loadItems(ctx, tx, cartID, c); err != nil {
+		return "", nil, 0, err
+	}
+	if err := loadPromotions(ctx, tx, cartID, c); err != nil {
+		return "", nil, 0, err
+	}
+
+	return cartID, c, version, tx.Commit()
+}
+
+func loadItems(ctx context.Context, tx *sql.Tx, cartID string, c *cart.Cart) error {
+	rows, err := tx.QueryContext(ctx, `SELECT sku, product, quantity, measure FROM cart_items WHERE cart_id = $1`, cartID)
+	if err != nil {
+		return fmt.Errorf("get cart items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			sku     string
+			product []byte
+			item    cart.CartItem
+		)
+		if err := rows.Scan(&sku, &product, &item.Quantity, &item.Measure); err != nil {
+			return fmt.Errorf("get cart items: %w", err)
+		}
+		if err := json.Unmarshal(product, &item.Product); err != nil {
+			return fmt.Errorf("decode product of %s: %w", sku, err)
+		}
+		c.Items[sku] = &item
+	}
+	return rows.Err()
+}
+
+func loadPromotions(ctx context.Context, tx *sql.Tx, cartID string, c *cart.Cart) error {
+	rows, err := tx.QueryContext(ctx, `SELECT scope, product_id, promotion FROM cart_promotions WHERE cart_id = $1 ORDER BY position`, cartID)
+	if err != nil {
+		return fmt.Errorf("get cart promotions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			scope, productID string
+			data             []byte
+		)
+		if err := rows.Scan(&scope, &productID, &data); err != nil {
+			return fmt.Errorf("get cart promotions: %w", err)
+		}
+		promotion := new(cart.Promotion)
+		if err := json.Unmarshal(data, promotion); err != nil {
+			return fmt.Errorf("decode promotion: %w", err)
+		}
+
+		switch scope {
+		case scopeCart:
+			c.CartPromotions = append(c.CartPromotions, promotion)
+		case scopeBundle:
+			c.Bundles = append(c.Bundles, promotion)
+		default:
+			c.Promotion[productID] = append(c.Promotion[productID], promotion)
+		}
+	}
+	return rows.Err()
+}
+
+func replaceItems(ctx context.Context, tx *sql.Tx, cartID string, items map[string]*cart.CartItem) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM cart_items WHERE cart_id = $1`, cartID); err != nil {
+		return fmt.Errorf("replace cart items: %w", err)
+	}
+
+	for sku, item := range items {
+		product, err := json.Marshal(item.Product)
+		if err != nil {
+			return fmt.Errorf("encode product of %s: %w", sku, err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO cart_items (cart_id, sku, product_id, product, quantity, measure)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			cartID, sku, item.Product.ID, string(product), item.Quantity, item.Measure); err != nil {
+			return fmt.Errorf("replace cart items: %w", err)
+		}
+	}
+	return nil
+}
+
+// replacePromotions stores the promotions in the order they are evaluated in:
+// product promotions by product ID, then bundles, then cart-wide promotions
+func replacePromotions(ctx context.Context, tx *sql.Tx, cartID string, c *cart.Cart) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM cart_promotions WHERE cart_id = $1`, cartID); err != nil {
+		return fmt.Errorf("replace cart promotions: %w", err)
+	}
+
+	productIDs := make([]string, 0, len(c.Promotion))
+	for productID := range c.Promotion {
+		productIDs = append(productIDs, productID)
+	}
+	sort.Strings(productIDs)
+
+	position := 0
+	insert := func(scope, productID string, promotion *cart.Promotion) error {
+		data, err := json.Marshal(promotion)
+		if err != nil {
+			return fmt.Errorf("encode promotion %s: %w", promotion.ID, err)
+		}
+		position++
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO cart_promotions (cart_id, position, scope, product_id, promotion)
+			VALUES ($1, $2, $3, $4, $5)`,
+			cartID, position, scope, productID, string(data)); err != nil {
+			return fmt.Errorf("replace cart promotions: %w", err)
+		}
+		return nil
+	}
+
+	for _, productID := range productIDs {
+		for _, promotion := range c.Promotion[productID] {
+			if err := insert(scopeProduct, productID, promotion); err != nil {
+				return err
+			}
+		}
+	}
+	for _, promotion := range c.Bundles {
+		if err := insert(scopeBundle, "", promotion); err != nil {
+			return err
+		}
+	}
+	for _, promotion := range c.CartPromotions {
+		if err := insert(scopeCart, "", promotion); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nonNilMap stores empty maps as {} rather than null
+func nonNilMap[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return map[K]V{}
+	}
+	return m
+}
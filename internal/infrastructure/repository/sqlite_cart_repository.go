@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkittipat/try-cart/internal/domain/repository"
+	_ "modernc.org/sqlite" // registers the pure-Go sqlite database/sql driver
+)
+
+// sqliteBusyTimeout is how long a write waits for another connection's write to finish
+const sqliteBusyTimeout = 5 * time.Second
+
+// OpenSQLite opens the SQLite database file at path, creating it if needed. Every
+// connection uses write-ahead logging, so reads do not block the writer, enforces
+// foreign keys and waits for busy locks. The schema must be brought up to date
+// with MigrateSQLite.
+func OpenSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	query := url.Values{"_pragma": {
+		fmt.Sprintf("busy_timeout(%d)", sqliteBusyTimeout.Milliseconds()),
+		"foreign_keys(1)",
+		"journal_mode(WAL)",
+		"synchronous(NORMAL)",
+	}}
+	query.Set("_time_format", "sqlite")
+
+	db, err := sql.Open("sqlite", path+"?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	// In-memory databases cannot use WAL and would differ per connection
+	var mode string
+	if err := db.QueryRowContext(ctx, `PRAGMA journal_mode`).Scan(&mode); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if !strings.EqualFold(mode, "wal") {
+		db.Close()
+		return nil, fmt.Errorf("sqlite database %s uses journal mode %s, not WAL", path, mode)
+	}
+	return db, nil
+}
+
+// NewSQLiteCartRepository returns a cart repository backed by db, opened with
+// OpenSQLite. It behaves like NewPostgresCartRepository on a single node.
+func NewSQLiteCartRepository(db *sql.DB) repository.Cart {
+	return &sqlCartRepository{
+		db:  db,
+		now: time.Now,
+	}
+}
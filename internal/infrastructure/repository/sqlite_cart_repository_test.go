@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkittipat/try-cart/internal/domain/repository"
+	"github.com/pkittipat/try-cart/internal/domain/repository/repositorytest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSQLite returns a migrated database in a temporary file
+func newTestSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	ctx := context.Background()
+	db, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "carts.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, MigrateSQLite(ctx, db))
+	// Migrating again is a no-op
+	require.NoError(t, MigrateSQLite(ctx, db))
+	return db
+}
+
+func TestSQLiteCartRepository(t *testing.T) {
+	repositorytest.TestCart(t, func() repository.Cart {
+		return NewSQLiteCartRepository(newTestSQLite(t))
+	})
+}
+
+func TestOpenSQLite(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "carts.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	var foreignKeys int
+	require.NoError(t, db.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&foreignKeys))
+	assert.Equal(t, 1, foreignKeys)
+
+	_, err = OpenSQLite(ctx, ":memory:")
+	assert.ErrorContains(t, err, "not WAL")
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkittipat/try-cart/internal/domain/inventory"
+)
+
+type reservation struct {
+	quantity  int64
+	expiresAt time.Time
+}
+
+type stockData struct {
+	onHand       int64
+	reservations map[string]reservation // cart ID -> reservation
+}
+
+type stockRepository struct {
+	mu    sync.Mutex
+	now   func() time.Time
+	stock map[string]*stockData // SKU -> stock
+}
+
+func NewStockRepository() inventory.Stock {
+	return &stockRepository{
+		now:   time.Now,
+		stock: make(map[string]*stockData),
+	}
+}
+
+func (r *stockRepository) SetStock(ctx context.Context, sku string, onHand int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, exists := r.stock[sku]
+	if !exists {
+		data = &stockData{reservations: make(map[string]reservation)}
+		r.stock[sku] = data
+	}
+
+	data.onHand = onHand
+	return nil
+}
+
+func (r *stockRepository) Level(ctx context.Context, sku string) (inventory.Level, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, exists := r.stock[sku]
+	if !exists {
+		return inventory.Level{}, false, nil
+	}
+
+	return inventory.Level{SKU: sku, OnHand: data.onHand, Reserved: data.reserved(r.now(), "")}, true, nil
+}
+
+// Reserve checks availability and stores the reservation under one lock,
+// so concurrent carts cannot reserve more than is on hand
+func (r *stockRepository) Reserve(ctx context.Context, cartID, sku string, quantity int64, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, exists := r.stock[sku]
+	if !exists {
+		return nil
+	}
+
+	level := inventory.Level{SKU: sku, OnHand: data.onHand, Reserved: data.reserved(r.now(), cartID)}
+	if quantity > level.Available() {
+		return &inventory.OutOfStockError{SKU: sku, Requested: quantity, Available: level.Available()}
+	}
+
+	data.reservations[cartID] = reservation{quantity: quantity, expiresAt: expiresAt}
+	return nil
+}
+
+// Extend leaves expired reservations alone, their units may already be held by
+// other carts
+func (r *stockRepository) Extend(ctx context.Context, cartID string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	for _, data := range r.stock {
+		if res, exists := data.reservations[cartID]; exists && now.Before(res.expiresAt) {
+			res.expiresAt = expiresAt
+			data.reservations[cartID] = res
+		}
+	}
+	return nil
+}
+
+func (r *stockRepository) Release(ctx context.Context, cartID, sku string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if data, exists := r.stock[sku]; exists {
+		delete(data.reservations, cartID)
+	}
+	return nil
+}
+
+func (r *stockRepository) ReleaseCart(ctx context.Context, cartID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, data := range r.stock {
+		delete(data.reservations, cartID)
+	}
+	return nil
+}
+
+func (r *stockRepository) ReleaseExpired(ctx context.Context, now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	released := 0
+	for _, data := range r.stock {
+		for cartID, res := range data.reservations {
+			if !now.Before(res.expiresAt) {
+				delete(data.reservations, cartID)
+				released++
+			}
+		}
+	}
+	return released, nil
+}
+
+// reserved sums the unexpired reservations, leaving out the given cart
+func (d *stockData) reserved(now time.Time, exceptCartID string) int64 {
+	var total int64
+	for cartID, res := range d.reservations {
+		if cartID != exceptCartID && now.Before(res.expiresAt) {
+			total += res.quantity
+		}
+	}
+	return total
+}
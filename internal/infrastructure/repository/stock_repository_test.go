@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkittipat/try-cart/internal/domain/inventory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStockRepository_Reserve(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	expires := now.Add(30 * time.Minute)
+
+	repo := NewStockRepository()
+	repo.(*stockRepository).now = func() time.Time { return now }
+	require.NoError(t, repo.SetStock(ctx, "SKU-1", 5))
+
+	require.NoError(t, repo.Reserve(ctx, "cart1", "SKU-1", 3, expires))
+	// A cart replaces its own reservation instead of adding to it
+	require.NoError(t, repo.Reserve(ctx, "cart1", "SKU-1", 4, expires))
+
+	err := repo.Reserve(ctx, "cart2", "SKU-1", 2, expires)
+	var outOfStock *inventory.OutOfStockError
+	require.ErrorAs(t, err, &outOfStock)
+	assert.ErrorIs(t, err, inventory.ErrOutOfStock)
+	assert.Equal(t, "SKU-1", outOfStock.SKU)
+	assert.Equal(t, int64(1), outOfStock.Available)
+
+	level, ok, err := repo.Level(ctx, "SKU-1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, inventory.Level{SKU: "SKU-1", OnHand: 5, Reserved: 4}, level)
+
+	require.NoError(t, repo.Release(ctx, "cart1", "SKU-1"))
+	require.NoError(t, repo.Reserve(ctx, "cart2", "SKU-1", 5, expires))
+
+	// Untracked SKUs are not limited
+	require.NoError(t, repo.Reserve(ctx, "cart1", "OTHER", 100, expires))
+	_, ok, err = repo.Level(ctx, "OTHER")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestStockRepository_Expiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	repo := NewStockRepository()
+	repo.(*stockRepository).now = func() time.Time { return now }
+	require.NoError(t, repo.SetStock(ctx, "SKU-1", 2))
+	require.NoError(t, repo.SetStock(ctx, "SKU-2", 2))
+	require.NoError(t, repo.Reserve(ctx, "cart1", "SKU-1", 2, now.Add(time.Minute)))
+	require.NoError(t, repo.Reserve(ctx, "cart1", "SKU-2", 1, now.Add(time.Hour)))
+
+	now = now.Add(time.Minute)
+	// An expired reservation no longer holds units, even before it is released
+	require.NoError(t, repo.Reserve(ctx, "cart2", "SKU-1", 2, now.Add(time.Minute)))
+
+	released, err := repo.ReleaseExpired(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, released)
+
+	level, _, err := repo.Level(ctx, "SKU-2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), level.Reserved)
+
+	require.NoError(t, repo.ReleaseCart(ctx, "cart1"))
+	level, _, err = repo.Level(ctx, "SKU-2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), level.Reserved)
+}
+
+func TestStockRepository_Extend(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	repo := NewStockRepository()
+	repo.(*stockRepository).now = func() time.Time { return now }
+	require.NoError(t, repo.SetStock(ctx, "SKU-1", 2))
+	require.NoError(t, repo.SetStock(ctx, "SKU-2", 2))
+	require.NoError(t, repo.Reserve(ctx, "cart1", "SKU-1", 1, now.Add(time.Minute)))
+	require.NoError(t, repo.Reserve(ctx, "cart1", "SKU-2", 1, now.Add(time.Hour)))
+	require.NoError(t, repo.Reserve(ctx, "cart2", "SKU-1", 1, now.Add(time.Minute)))
+
+	require.NoError(t, repo.Extend(ctx, "cart1", now.Add(2*time.Hour)))
+	now = now.Add(time.Hour)
+	// Both of cart1's reservations moved, cart2's did not
+	level, _, err := repo.Level(ctx, "SKU-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), level.Reserved)
+	level, _, err = repo.Level(ctx, "SKU-2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), level.Reserved)
+
+	// An expired reservation is not brought back
+	require.NoError(t, repo.Extend(ctx, "cart2", now.Add(time.Hour)))
+	released, err := repo.ReleaseExpired(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, released)
+	level, _, err = repo.Level(ctx, "SKU-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), level.Reserved)
+}
+
+func TestStockRepository_ConcurrentReserve(t *testing.T) {
+	ctx := context.Background()
+	repo := NewStockRepository()
+	require.NoError(t, repo.SetStock(ctx, "SKU-1", 10))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if repo.Reserve(ctx, fmt.Sprintf("cart%d", i), "SKU-1", 1, time.Now().Add(time.Hour)) == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, succeeded)
+}
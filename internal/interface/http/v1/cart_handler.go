@@ -1,14 +1,216 @@
 package http
 
 import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/labstack/echo/v4"
 	"github.com/pkittipat/try-cart/internal/app/service"
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/pkittipat/try-cart/internal/domain/catalog"
+	"github.com/pkittipat/try-cart/internal/domain/exchange"
+	"github.com/pkittipat/try-cart/internal/domain/inventory"
+	"github.com/pkittipat/try-cart/internal/domain/repository"
+	"github.com/pkittipat/try-cart/internal/domain/shipping"
+	"github.com/shopspring/decimal"
+)
+
+// Conditional request headers, a cart's ETag is its version
+const (
+	headerETag    = "ETag"
+	headerIfMatch = "If-Match"
 )
 
+var errPreconditionFailed = errors.New("If-Match does not name a version of the cart")
+
 type cartHandler struct {
 	cartSrv *service.CartService
 }
 
+type (
+	createCartRequest struct {
+		UserID string `json:"user_id"`
+	}
+
+	addItemRequest struct {
+		ProductID string          `json:"product_id"`
+		SKU       string          `json:"sku"` // required for products with variants
+		Quantity  int64           `json:"quantity"`
+		Measure   decimal.Decimal `json:"measure"` // amount of a measured product, instead of quantity
+	}
+
+	updateItemRequest struct {
+		Quantity int64           `json:"quantity"`
+		Measure  decimal.Decimal `json:"measure"`
+	}
+
+	setTaxRequest struct {
+		Region string `json:"region"`
+	}
+
+	promotionRequest struct {
+		ID            string             `json:"id"`
+		ProductID     string             `json:"product_id"`
+		SKU           string             `json:"sku"`
+		PromotionType cart.PromotionType `json:"type"`
+		Discount      int64              `json:"discount"`
+		Stacking      cart.StackingRule  `json:"stacking"`
+		Priority      int64              `json:"priority"`
+
+		BuyQuantity     int64  `json:"buy_quantity,omitempty"`
+		GetQuantity     int64  `json:"get_quantity,omitempty"`
+		MaxApplications int64  `json:"max_applications,omitempty"`
+		BuyProductID    string `json:"buy_product_id,omitempty"`
+
+		Amount      decimal.Decimal `json:"amount"`
+		MinSpend    decimal.Decimal `json:"min_spend"`
+		MaxDiscount decimal.Decimal `json:"max_discount"`
+
+		BundleItems []bundleItemPayload `json:"bundle_items,omitempty"`
+
+		StartsAt time.Time        `json:"starts_at"`
+		EndsAt   time.Time        `json:"ends_at"`
+		Schedule *schedulePayload `json:"schedule,omitempty"`
+	}
+
+	bundleItemPayload struct {
+		ProductID string `json:"product_id"`
+		Quantity  int64  `json:"quantity"`
+	}
+
+	schedulePayload struct {
+		Weekdays  []time.Weekday `json:"weekdays,omitempty"` // 0 is Sunday
+		StartHour int            `json:"start_hour"`
+		EndHour   int            `json:"end_hour"`
+		Location  string         `json:"location,omitempty"`
+	}
+
+	cartResponse struct {
+		ID         string              `json:"id,omitempty"`
+		Currency   string              `json:"currency,omitempty"`
+		Items      []itemResponse      `json:"items"`
+		Promotions []promotionResponse `json:"promotions"`
+		Coupons    []string            `json:"coupons"`
+		TaxRegion  string              `json:"tax_region,omitempty"`
+		Total      string              `json:"total"`
+	}
+
+	itemResponse struct {
+		ProductID       string             `json:"product_id"`
+		SKU             string             `json:"sku"`
+		Options         map[string]string  `json:"options,omitempty"`
+		Description     string             `json:"description"`
+		Price           string             `json:"price"`
+		Discount        int64              `json:"discount"`
+		DiscountedPrice string             `json:"discounted_price"`
+		TaxCategory     cart.TaxCategory   `json:"tax_category,omitempty"`
+		Quantity        int64              `json:"quantity"`
+		Measure         string             `json:"measure,omitempty"`
+		Unit            cart.UnitOfMeasure `json:"unit,omitempty"`
+	}
+
+	promotionResponse struct {
+		ID            string             `json:"id"`
+		ProductID     string             `json:"product_id,omitempty"`
+		SKU           string             `json:"sku,omitempty"`
+		PromotionType cart.PromotionType `json:"type"`
+		Discount      int64              `json:"discount"`
+		Stacking      cart.StackingRule  `json:"stacking"`
+		Priority      int64              `json:"priority"`
+
+		BuyQuantity     int64  `json:"buy_quantity,omitempty"`
+		GetQuantity     int64  `json:"get_quantity,omitempty"`
+		MaxApplications int64  `json:"max_applications,omitempty"`
+		BuyProductID    string `json:"buy_product_id,omitempty"`
+
+		Amount      string `json:"amount,omitempty"`
+		MinSpend    string `json:"min_spend,omitempty"`
+		MaxDiscount string `json:"max_discount,omitempty"`
+
+		BundleItems []bundleItemPayload `json:"bundle_items,omitempty"`
+
+		StartsAt string           `json:"starts_at,omitempty"`
+		EndsAt   string           `json:"ends_at,omitempty"`
+		Schedule *schedulePayload `json:"schedule,omitempty"`
+	}
+
+	totalResponse struct {
+		ID             string           `json:"id"`
+		Currency       string           `json:"currency,omitempty"`
+		Lines          []lineResponse   `json:"lines"`
+		Bundles        []bundleResponse `json:"bundles"`
+		Subtotal       string           `json:"subtotal"`
+		CartPromotions []savingResponse `json:"cart_promotions"`
+		TotalSaving    string           `json:"total_saving"`
+		Taxes          []taxResponse    `json:"taxes"`
+		Tax            string           `json:"tax"`
+		Total          string           `json:"total"`
+	}
+
+	taxResponse struct {
+		Category cart.TaxCategory `json:"category"`
+		Rate     string           `json:"rate"`
+		Net      string           `json:"net"`
+		Amount   string           `json:"amount"`
+	}
+
+	lineResponse struct {
+		ProductID       string             `json:"product_id"`
+		SKU             string             `json:"sku"`
+		Options         map[string]string  `json:"options,omitempty"`
+		Description     string             `json:"description"`
+		Quantity        int64              `json:"quantity"`
+		Measure         string             `json:"measure,omitempty"`
+		Unit            cart.UnitOfMeasure `json:"unit,omitempty"`
+		BundledQuantity int64              `json:"bundled_quantity"`
+		UnitPrice       string             `json:"unit_price"`
+		DiscountedPrice string             `json:"discounted_price"`
+		TierSaving      string             `json:"tier_saving"`
+		ProductSaving   string             `json:"product_saving"`
+		Promotions      []savingResponse   `json:"promotions"`
+		LineTotal       string             `json:"line_total"`
+	}
+
+	bundleResponse struct {
+		PromotionID string                    `json:"promotion_id"`
+		Count       int64                     `json:"count"`
+		BundlePrice string                    `json:"bundle_price"`
+		Components  []bundleComponentResponse `json:"components"`
+		Saving      string                    `json:"saving"`
+		Total       string                    `json:"total"`
+	}
+
+	bundleComponentResponse struct {
+		ProductID string `json:"product_id"`
+		SKU       string `json:"sku"`
+		Quantity  int64  `json:"quantity"`
+	}
+
+	savingResponse struct {
+		PromotionID   string             `json:"promotion_id"`
+		PromotionType cart.PromotionType `json:"type"`
+		Amount        string             `json:"amount"`
+	}
+
+	applyCouponRequest struct {
+		Code string `json:"code"` // redeemed for the cart's owner
+	}
+
+	errorResponse struct {
+		Message   string               `json:"message"`
+		Reason    cart.RejectionReason `json:"reason,omitempty"`
+		Code      cart.ViolationCode   `json:"code,omitempty"`      // set for purchase limit violations
+		Limit     string               `json:"limit,omitempty"`     // the limit that was reached
+		SKU       string               `json:"sku,omitempty"`       // set for out of stock errors
+		Available *int64               `json:"available,omitempty"` // units left of SKU
+		Version   int64                `json:"version,omitempty"`   // current version of a cart that changed
+	}
+)
+
 func RegisterCartHandler(
 	router *echo.Group,
 	cartSrv *service.CartService,
@@ -18,10 +220,601 @@ func RegisterCartHandler(
 	}
 
 	router.POST("/", handler.CreateCart)
+	router.GET("/users/:userID", handler.GetCartByUserID)
+	router.GET("/:cartID", handler.GetCart)
+	router.DELETE("/:cartID", handler.DeleteCart)
+	router.GET("/:cartID/total", handler.GetTotal)
+	router.POST("/:cartID/items", handler.AddItem)
+	router.PUT("/:cartID/items/:sku", handler.UpdateItem)
+	router.DELETE("/:cartID/items/:sku", handler.RemoveItem)
+	router.DELETE("/:cartID/items", handler.ClearItems)
+	router.POST("/:cartID/promotions", handler.ApplyPromotion)
+	router.DELETE("/:cartID/promotions/:promotionID", handler.RemovePromotion)
+	router.POST("/:cartID/coupons", handler.ApplyCoupon)
+	router.PUT("/:cartID/tax", handler.SetTax)
 }
 
-// Added Product to cart
-// If there is no cart then create and push product into cart.
+// Create a cart for the user.
+// If the user already has a cart then the existing one is returned.
 func (h *cartHandler) CreateCart(e echo.Context) error {
-	return nil
+	var req createCartRequest
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(http.StatusBadRequest, errorResponse{Message: "invalid request body"})
+	}
+
+	ctx := e.Request().Context()
+	status := http.StatusCreated
+	cartID, err := h.cartSrv.CreateCart(ctx, req.UserID)
+	if errors.Is(err, repository.ErrCartExists) {
+		status = http.StatusOK
+	} else if err != nil {
+		return errorJSON(e, err)
+	}
+
+	c, version, err := h.cartSrv.GetCart(ctx, cartID)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return cartJSON(e, status, cartID, c, version)
+}
+
+// GetCart returns the cart with its version as the ETag. Requests that change the
+// cart can send it back in If-Match to fail with 412 when the cart changed since.
+func (h *cartHandler) GetCart(e echo.Context) error {
+	cartID := e.Param("cartID")
+	c, version, err := h.cartSrv.GetCart(e.Request().Context(), cartID)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return cartJSON(e, http.StatusOK, cartID, c, version)
+}
+
+// DeleteCart deletes the cart and releases the stock it reserved. It honours If-Match
+// like the routes that change the cart.
+func (h *cartHandler) DeleteCart(e echo.Context) error {
+	version, err := ifMatchVersion(e)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	if err := h.cartSrv.DeleteCart(e.Request().Context(), e.Param("cartID"), version); err != nil {
+		return errorJSON(e, err)
+	}
+
+	return e.NoContent(http.StatusNoContent)
+}
+
+// GetCartByUserID returns the user's cart with its version as the ETag, like GetCart
+func (h *cartHandler) GetCartByUserID(e echo.Context) error {
+	cartID, c, version, err := h.cartSrv.GetCartByUserID(e.Request().Context(), e.Param("userID"))
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return cartJSON(e, http.StatusOK, cartID, c, version)
+}
+
+// GetTotal returns the price breakdown with the version it priced as the ETag
+func (h *cartHandler) GetTotal(e echo.Context) error {
+	cartID := e.Param("cartID")
+	breakdown, version, err := h.cartSrv.GetBreakdown(e.Request().Context(), cartID)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	setETag(e, version)
+	return e.JSON(http.StatusOK, newTotalResponse(cartID, breakdown))
+}
+
+func (h *cartHandler) AddItem(e echo.Context) error {
+	var req addItemRequest
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(http.StatusBadRequest, errorResponse{Message: "invalid request body"})
+	}
+
+	version, err := ifMatchVersion(e)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	cartID := e.Param("cartID")
+	ctx := e.Request().Context()
+	var c *cart.Cart
+	if req.Measure.IsZero() {
+		c, version, err = h.cartSrv.AddItem(ctx, cartID, version, req.ProductID, req.SKU, req.Quantity)
+	} else {
+		c, version, err = h.cartSrv.AddMeasuredItem(ctx, cartID, version, req.ProductID, req.SKU, req.Measure)
+	}
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return cartJSON(e, http.StatusOK, cartID, c, version)
+}
+
+func (h *cartHandler) UpdateItem(e echo.Context) error {
+	var req updateItemRequest
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(http.StatusBadRequest, errorResponse{Message: "invalid request body"})
+	}
+
+	version, err := ifMatchVersion(e)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	cartID := e.Param("cartID")
+	ctx := e.Request().Context()
+	var c *cart.Cart
+	if req.Measure.IsZero() {
+		c, version, err = h.cartSrv.UpdateItem(ctx, cartID, version, e.Param("sku"), req.Quantity)
+	} else {
+		c, version, err = h.cartSrv.UpdateMeasuredItem(ctx, cartID, version, e.Param("sku"), req.Measure)
+	}
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return cartJSON(e, http.StatusOK, cartID, c, version)
+}
+
+func (h *cartHandler) RemoveItem(e echo.Context) error {
+	version, err := ifMatchVersion(e)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	cartID := e.Param("cartID")
+	c, version, err := h.cartSrv.RemoveItem(e.Request().Context(), cartID, version, e.Param("sku"))
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return cartJSON(e, http.StatusOK, cartID, c, version)
+}
+
+func (h *cartHandler) ClearItems(e echo.Context) error {
+	version, err := ifMatchVersion(e)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	cartID := e.Param("cartID")
+	c, version, err := h.cartSrv.ClearItems(e.Request().Context(), cartID, version)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return cartJSON(e, http.StatusOK, cartID, c, version)
+}
+
+func (h *cartHandler) ApplyPromotion(e echo.Context) error {
+	var req promotionRequest
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(http.StatusBadRequest, errorResponse{Message: "invalid request body"})
+	}
+	if req.ID == "" {
+		return e.JSON(http.StatusBadRequest, errorResponse{Message: "promotion ID is required"})
+	}
+
+	version, err := ifMatchVersion(e)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	cartID := e.Param("cartID")
+	c, version, err := h.cartSrv.ApplyPromotion(e.Request().Context(), cartID, version, req.toPromotion())
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return cartJSON(e, http.StatusOK, cartID, c, version)
+}
+
+func (r promotionRequest) toPromotion() cart.Promotion {
+	promotion := cart.Promotion{
+		ID:            r.ID,
+		ProductID:     r.ProductID,
+		SKU:           r.SKU,
+		PromotionType: r.PromotionType,
+		Discount:      r.Discount,
+		Stacking:      r.Stacking,
+		Priority:      r.Priority,
+
+		BuyQuantity:     r.BuyQuantity,
+		GetQuantity:     r.GetQuantity,
+		MaxApplications: r.MaxApplications,
+		BuyProductID:    r.BuyProductID,
+
+		Amount:      r.Amount,
+		MinSpend:    r.MinSpend,
+		MaxDiscount: r.MaxDiscount,
+
+		StartsAt: r.StartsAt,
+		EndsAt:   r.EndsAt,
+	}
+	if r.Schedule != nil {
+		promotion.Schedule = &cart.Schedule{
+			Weekdays:  r.Schedule.Weekdays,
+			StartHour: r.Schedule.StartHour,
+			EndHour:   r.Schedule.EndHour,
+			Location:  r.Schedule.Location,
+		}
+	}
+	for _, item := range r.BundleItems {
+		promotion.BundleItems = append(promotion.BundleItems, cart.BundleItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	return promotion
+}
+
+func (h *cartHandler) RemovePromotion(e echo.Context) error {
+	version, err := ifMatchVersion(e)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	cartID := e.Param("cartID")
+	c, version, err := h.cartSrv.RemovePromotion(e.Request().Context(), cartID, version, e.Param("promotionID"))
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return cartJSON(e, http.StatusOK, cartID, c, version)
+}
+
+func (h *cartHandler) ApplyCoupon(e echo.Context) error {
+	var req applyCouponRequest
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(http.StatusBadRequest, errorResponse{Message: "invalid request body"})
+	}
+
+	version, err := ifMatchVersion(e)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	cartID := e.Param("cartID")
+	c, version, err := h.cartSrv.ApplyCoupon(e.Request().Context(), cartID, version, req.Code)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return cartJSON(e, http.StatusOK, cartID, c, version)
+}
+
+// SetTax selects the tax region of the cart, an empty region removes tax
+func (h *cartHandler) SetTax(e echo.Context) error {
+	var req setTaxRequest
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(http.StatusBadRequest, errorResponse{Message: "invalid request body"})
+	}
+
+	version, err := ifMatchVersion(e)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	cartID := e.Param("cartID")
+	c, version, err := h.cartSrv.SetTaxRegion(e.Request().Context(), cartID, version, req.Region)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return cartJSON(e, http.StatusOK, cartID, c, version)
+}
+
+// cartJSON responds with the cart and its version as a strong ETag
+func cartJSON(e echo.Context, status int, cartID string, c *cart.Cart, version int64) error {
+	setETag(e, version)
+	return e.JSON(status, newCartResponse(cartID, c))
+}
+
+// setETag sets the cart version as a strong ETag
+func setETag(e echo.Context, version int64) {
+	e.Response().Header().Set(headerETag, strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// ifMatchVersion returns the cart version named by If-Match, service.AnyVersion when
+// the header is missing or *. Weak tags never match, as If-Match compares strongly.
+func ifMatchVersion(e echo.Context) (int64, error) {
+	tag := strings.TrimSpace(e.Request().Header.Get(headerIfMatch))
+	if tag == "" || tag == "*" {
+		return service.AnyVersion, nil
+	}
+
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, errPreconditionFailed
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version < 1 {
+		return 0, errPreconditionFailed
+	}
+	return version, nil
+}
+
+func newCartResponse(cartID string, c *cart.Cart) cartResponse {
+	resp := cartResponse{
+		ID:         cartID,
+		Currency:   c.Currency,
+		Items:      make([]itemResponse, 0, len(c.Items)),
+		Promotions: make([]promotionResponse, 0, len(c.Promotion)+len(c.Bundles)+len(c.CartPromotions)),
+		Coupons:    make([]string, 0, len(c.Coupons)),
+		Total:      cart.DisplayAmount(c.CalculateTotal(), c.Currency),
+	}
+	if c.Tax != nil {
+		resp.TaxRegion = c.Tax.Region
+	}
+
+	for sku, item := range c.Items {
+		resp.Items = append(resp.Items, itemResponse{
+			ProductID:       item.Product.ID,
+			SKU:             sku,
+			Options:         item.Product.Options,
+			Description:     item.Product.Description,
+			Price:           cart.DisplayAmount(item.Product.Price, c.Currency),
+			Discount:        item.Product.Discount,
+			DiscountedPrice: cart.DisplayAmount(item.Product.GetDiscountedPrice(), c.Currency),
+			TaxCategory:     item.Product.TaxCategory,
+			Quantity:        item.Quantity,
+			Measure:         displayOptionalMeasure(item.Measure),
+			Unit:            item.Product.Unit,
+		})
+	}
+	sort.Slice(resp.Items, func(i, j int) bool {
+		if resp.Items[i].ProductID != resp.Items[j].ProductID {
+			return resp.Items[i].ProductID < resp.Items[j].ProductID
+		}
+		return resp.Items[i].SKU < resp.Items[j].SKU
+	})
+
+	productIDs := make([]string, 0, len(c.Promotion))
+	for productID := range c.Promotion {
+		productIDs = append(productIDs, productID)
+	}
+	sort.Strings(productIDs)
+	for _, productID := range productIDs {
+		for _, promo := range c.Promotion[productID] {
+			resp.Promotions = append(resp.Promotions, newPromotionResponse(promo, c.Currency))
+		}
+	}
+	for _, promo := range c.Bundles {
+		resp.Promotions = append(resp.Promotions, newPromotionResponse(promo, c.Currency))
+	}
+	for _, promo := range c.CartPromotions {
+		resp.Promotions = append(resp.Promotions, newPromotionResponse(promo, c.Currency))
+	}
+
+	for code := range c.Coupons {
+		resp.Coupons = append(resp.Coupons, code)
+	}
+	sort.Strings(resp.Coupons)
+
+	return resp
+}
+
+func newTotalResponse(cartID string, b cart.PriceBreakdown) totalResponse {
+	resp := totalResponse{
+		ID:             cartID,
+		Currency:       b.Currency,
+		Lines:          make([]lineResponse, 0, len(b.Lines)),
+		Bundles:        make([]bundleResponse, 0, len(b.Bundles)),
+		Subtotal:       cart.DisplayAmount(b.Subtotal, b.Currency),
+		CartPromotions: newSavingResponses(b.CartPromotions, b.Currency),
+		TotalSaving:    cart.DisplayAmount(b.TotalSaving(), b.Currency),
+		Taxes:          make([]taxResponse, 0, len(b.Taxes)),
+		Tax:            cart.DisplayAmount(b.Tax, b.Currency),
+		Total:          cart.DisplayAmount(b.Total, b.Currency),
+	}
+	for _, tax := range b.Taxes {
+		resp.Taxes = append(resp.Taxes, taxResponse{
+			Category: tax.Category,
+			Rate:     tax.Rate.String(),
+			Net:      cart.DisplayAmount(tax.Net, b.Currency),
+			Amount:   cart.DisplayAmount(tax.Amount, b.Currency),
+		})
+	}
+
+	for _, line := range b.Lines {
+		resp.Lines = append(resp.Lines, lineResponse{
+			ProductID:       line.ProductID,
+			SKU:             line.SKU,
+			Options:         line.Options,
+			Description:     line.Description,
+			Quantity:        line.Quantity,
+			Measure:         displayOptionalMeasure(line.Measure),
+			Unit:            line.Unit,
+			BundledQuantity: line.BundledQuantity,
+			UnitPrice:       cart.DisplayAmount(line.UnitPrice, b.Currency),
+			DiscountedPrice: cart.DisplayAmount(line.DiscountedPrice, b.Currency),
+			TierSaving:      cart.DisplayAmount(line.TierSaving, b.Currency),
+			ProductSaving:   cart.DisplayAmount(line.ProductSaving, b.Currency),
+			Promotions:      newSavingResponses(line.Promotions, b.Currency),
+			LineTotal:       cart.DisplayAmount(line.LineTotal, b.Currency),
+		})
+	}
+
+	for _, bundle := range b.Bundles {
+		bundleResp := bundleResponse{
+			PromotionID: bundle.PromotionID,
+			Count:       bundle.Count,
+			BundlePrice: cart.DisplayAmount(bundle.BundlePrice, b.Currency),
+			Components:  make([]bundleComponentResponse, 0, len(bundle.Components)),
+			Saving:      cart.DisplayAmount(bundle.Saving, b.Currency),
+			Total:       cart.DisplayAmount(bundle.Total, b.Currency),
+		}
+		for _, component := range bundle.Components {
+			bundleResp.Components = append(bundleResp.Components, bundleComponentResponse{
+				ProductID: component.ProductID,
+				SKU:       component.SKU,
+				Quantity:  component.Quantity,
+			})
+		}
+		resp.Bundles = append(resp.Bundles, bundleResp)
+	}
+
+	return resp
+}
+
+func newSavingResponses(savings []cart.PromotionSaving, currency string) []savingResponse {
+	resp := make([]savingResponse, 0, len(savings))
+	for _, saving := range savings {
+		resp = append(resp, savingResponse{
+			PromotionID:   saving.PromotionID,
+			PromotionType: saving.PromotionType,
+			Amount:        cart.DisplayAmount(saving.Amount, currency),
+		})
+	}
+
+	return resp
+}
+
+func newPromotionResponse(p *cart.Promotion, currency string) promotionResponse {
+	stacking := cart.Exclusive
+	if !p.IsExclusive() {
+		stacking = cart.Stackable
+	}
+
+	resp := promotionResponse{
+		ID:            p.ID,
+		ProductID:     p.ProductID,
+		SKU:           p.SKU,
+		PromotionType: p.PromotionType,
+		Discount:      p.Discount,
+		Stacking:      stacking,
+		Priority:      p.Priority,
+
+		BuyQuantity:     p.BuyQuantity,
+		GetQuantity:     p.GetQuantity,
+		MaxApplications: p.MaxApplications,
+		BuyProductID:    p.BuyProductID,
+
+		Amount:      displayOptionalPrice(p.Amount, currency),
+		MinSpend:    displayOptionalPrice(p.MinSpend, currency),
+		MaxDiscount: displayOptionalPrice(p.MaxDiscount, currency),
+
+		StartsAt: displayOptionalTime(p.StartsAt),
+		EndsAt:   displayOptionalTime(p.EndsAt),
+	}
+	if p.Schedule != nil {
+		resp.Schedule = &schedulePayload{
+			Weekdays:  p.Schedule.Weekdays,
+			StartHour: p.Schedule.StartHour,
+			EndHour:   p.Schedule.EndHour,
+			Location:  p.Schedule.Location,
+		}
+	}
+	for _, item := range p.BundleItems {
+		resp.BundleItems = append(resp.BundleItems, bundleItemPayload{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	return resp
+}
+
+// displayOptionalPrice renders unset (zero) amounts as an empty string
+func displayOptionalPrice(price decimal.Decimal, currency string) string {
+	if price.IsZero() {
+		return ""
+	}
+	return cart.DisplayAmount(price, currency)
+}
+
+// displayOptionalMeasure renders the amount of a measured line, empty for lines sold by the unit
+func displayOptionalMeasure(measure decimal.Decimal) string {
+	if measure.IsZero() {
+		return ""
+	}
+	return measure.String()
+}
+
+// displayOptionalTime renders unset (zero) times as an empty string
+func displayOptionalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// errorJSON maps service and domain errors to an HTTP status. Errors it does not
+// know are logged and reported as a 500 without their details.
+func errorJSON(e echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, repository.ErrCartNotFound),
+		errors.Is(err, cart.ErrProductNotFound),
+		errors.Is(err, service.ErrPromotionNotFound),
+		errors.Is(err, repository.ErrCouponNotFound),
+		errors.Is(err, catalog.ErrProductNotFound),
+		errors.Is(err, cart.ErrVariantNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, repository.ErrCartExists),
+		errors.Is(err, repository.ErrCouponExists),
+		errors.Is(err, catalog.ErrProductExists):
+		status = http.StatusConflict
+	case errors.Is(err, errPreconditionFailed):
+		status = http.StatusPreconditionFailed
+	case errors.Is(err, repository.ErrInvalidCartID),
+		errors.Is(err, repository.ErrInvalidUserID),
+		errors.Is(err, cart.ErrInvalidProduct),
+		errors.Is(err, cart.ErrInvalidQuantity),
+		errors.Is(err, cart.ErrInvalidPromotion),
+		errors.Is(err, cart.ErrInvalidCoupon),
+		errors.Is(err, cart.ErrCurrencyMismatch),
+		errors.Is(err, cart.ErrUnknownCurrency),
+		errors.Is(err, cart.ErrUnknownTaxRegion),
+		errors.Is(err, shipping.ErrInvalidDestination):
+		status = http.StatusBadRequest
+	case errors.Is(err, shipping.ErrNoZone),
+		errors.Is(err, shipping.ErrNoRate),
+		errors.Is(err, cart.ErrWholeUnitPromotion),
+		errors.Is(err, exchange.ErrRateNotFound),
+		errors.Is(err, exchange.ErrNoBaseCurrency):
+		status = http.StatusUnprocessableEntity
+	}
+
+	resp := errorResponse{Message: err.Error()}
+	var rejection *cart.CouponRejection
+	if errors.As(err, &rejection) {
+		status = http.StatusUnprocessableEntity
+		resp.Reason = rejection.Reason
+	}
+	var violation *cart.LimitViolation
+	if errors.As(err, &violation) {
+		status = http.StatusUnprocessableEntity
+		resp.Code = violation.Code
+		resp.SKU = violation.SKU
+		resp.Limit = violation.Limit
+	}
+	var conflict *repository.VersionConflictError
+	if errors.As(err, &conflict) {
+		// The version the client sent in If-Match is stale, or without one the
+		// change kept racing others and can be retried
+		status = http.StatusConflict
+		if e.Request().Header.Get(headerIfMatch) != "" {
+			status = http.StatusPreconditionFailed
+		}
+		resp.Version = conflict.Current
+	}
+	var outOfStock *inventory.OutOfStockError
+	if errors.As(err, &outOfStock) {
+		status = http.StatusConflict
+		resp.SKU = outOfStock.SKU
+		resp.Available = &outOfStock.Available
+	}
+	if status == http.StatusInternalServerError {
+		// Unexpected errors can carry storage details, the client only learns that
+		// the request failed
+		e.Logger().Errorf("%s %s: %v", e.Request().Method, e.Request().URL.Path, err)
+		resp = errorResponse{Message: "internal server error"}
+	}
+
+	return e.JSON(status, resp)
 }
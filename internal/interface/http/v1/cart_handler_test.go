@@ -0,0 +1,1175 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkittipat/try-cart/internal/app/service"
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/pkittipat/try-cart/internal/domain/inventory"
+	domainrepository "github.com/pkittipat/try-cart/internal/domain/repository"
+	"github.com/pkittipat/try-cart/internal/domain/shipping"
+	"github.com/pkittipat/try-cart/internal/infrastructure/exchange"
+	"github.com/pkittipat/try-cart/internal/infrastructure/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) *echo.Echo {
+	t.Helper()
+	return newTestServerWith(t, repository.NewCartRepository(), repository.NewStockRepository(), repository.NewPurchaseHistoryRepository())
+}
+
+// newTestServerWith wires the API with the given carts, stock and the purchase
+// history MaxPerCustomer counts
+func newTestServerWith(t *testing.T, cartRepo domainrepository.Cart, stockRepo inventory.Stock, history domainrepository.PurchaseHistory) *echo.Echo {
+	t.Helper()
+	e := echo.New()
+	productRepo := repository.NewProductRepository()
+	cartSrv := service.NewCartService(cartRepo, repository.NewCouponRepository(), productRepo, stockRepo, history, cart.DefaultTaxTable(), cart.Limits{MaxLineQuantity: 10, MaxLines: 5})
+	catalogSrv := service.NewCatalogService(productRepo, stockRepo)
+	RegisterCartHandler(e.Group("/v1/carts"), cartSrv)
+	RegisterShippingHandler(e.Group("/v1/carts"), service.NewShippingService(cartRepo, shipping.DefaultCalculator()))
+	rates, err := exchange.NewStaticProvider("THB", time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), map[string]decimal.Decimal{
+		"USD": decimal.RequireFromString("0.03"),
+	})
+	require.NoError(t, err)
+	RegisterQuoteHandler(e.Group("/v1/carts"), service.NewQuoteService(cartRepo, rates))
+	RegisterCouponHandler(e.Group("/v1/coupons"), cartSrv)
+	RegisterCatalogHandler(e.Group("/v1/products"), catalogSrv)
+	RegisterInventoryHandler(e.Group("/v1/inventory"), catalogSrv)
+	RegisterPurchaseHandler(e.Group("/v1/users"), cartSrv)
+	return e
+}
+
+func doRequest(e *echo.Echo, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func createTestCart(t *testing.T, e *echo.Echo, userID string) string {
+	t.Helper()
+	rec := doRequest(e, http.MethodPost, "/v1/carts/", `{"user_id":"`+userID+`"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var resp cartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotEmpty(t, resp.ID)
+	return resp.ID
+}
+
+func createTestProduct(t *testing.T, e *echo.Echo, body string) {
+	t.Helper()
+	rec := doRequest(e, http.MethodPost, "/v1/products/", body)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+}
+
+func TestCartHandler_ConcurrentRequests(t *testing.T) {
+	e := newTestServer(t)
+	createTestProduct(t, e, `{"id":"A","price":"100.00"}`)
+	rec := doRequest(e, http.MethodPut, "/v1/inventory/A", `{"on_hand":20}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	cartID := createTestCart(t, e, "user1")
+
+	// Adds race each other and the reads that price the cart
+	const numRequests = 8
+	var wg sync.WaitGroup
+	addCodes := make(chan int, numRequests)
+	for i := 0; i < numRequests; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			addCodes <- doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"A","quantity":1}`).Code
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, http.StatusOK, doRequest(e, http.MethodGet, "/v1/carts/"+cartID+"/total", "").Code)
+		}()
+	}
+	wg.Wait()
+	close(addCodes)
+
+	// Adds that race are applied again to the newer cart
+	for code := range addCodes {
+		assert.Equal(t, http.StatusOK, code)
+	}
+
+	rec = doRequest(e, http.MethodGet, "/v1/carts/"+cartID, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp cartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, int64(numRequests), resp.Items[0].Quantity)
+
+	rec = doRequest(e, http.MethodGet, "/v1/inventory/A", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stock stockResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stock))
+	assert.Equal(t, int64(numRequests), stock.Reserved)
+}
+
+// racingCartRepository stores a change of its own before the next races updates
+// are stored, as a concurrent request to the same cart would
+type racingCartRepository struct {
+	domainrepository.Cart
+	races  int
+	onRace func() // runs with each racing change, nil for none
+}
+
+func (r *racingCartRepository) UpdateIfVersion(ctx context.Context, cartID string, c *cart.Cart, expectedVersion int64) (int64, error) {
+	if r.races > 0 {
+		r.races--
+		if r.onRace != nil {
+			r.onRace()
+		}
+		stored, version, err := r.Cart.GetByIDWithVersion(ctx, cartID)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := r.Cart.UpdateIfVersion(ctx, cartID, stored, version); err != nil {
+			return 0, err
+		}
+	}
+	return r.Cart.UpdateIfVersion(ctx, cartID, c, expectedVersion)
+}
+
+// failingCartRepository fails every read with an error a database might return
+type failingCartRepository struct {
+	domainrepository.Cart
+}
+
+func (r *failingCartRepository) GetByIDWithVersion(ctx context.Context, cartID string) (*cart.Cart, int64, error) {
+	return nil, 0, errors.New("dial tcp 10.0.0.5:5432: connection refused")
+}
+
+func TestCartHandler_InternalError(t *testing.T) {
+	e := newTestServerWith(t, &failingCartRepository{Cart: repository.NewCartRepository()}, repository.NewStockRepository(), repository.NewPurchaseHistoryRepository())
+	var logged bytes.Buffer
+	e.Logger.SetOutput(&logged)
+
+	rec := doRequest(e, http.MethodGet, "/v1/carts/cart1/total", "")
+	require.Equal(t, http.StatusInternalServerError, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"message":"internal server error"}`, rec.Body.String())
+	// The detail is only logged
+	assert.Contains(t, logged.String(), "connection refused")
+}
+
+func TestCartHandler_RacedChanges(t *testing.T) {
+	cartRepo := &racingCartRepository{Cart: repository.NewCartRepository()}
+	e := newTestServerWith(t, cartRepo, repository.NewStockRepository(), repository.NewPurchaseHistoryRepository())
+	createTestProduct(t, e, `{"id":"A","price":"100.00"}`)
+	rec := doRequest(e, http.MethodPut, "/v1/inventory/A", `{"on_hand":20}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPost, "/v1/coupons/", `{"code":"ONCE","max_redemptions_per_user":1,"promotion":{"type":"totalDiscount","discount":10}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	cartID := createTestCart(t, e, "user1")
+	base := "/v1/carts/" + cartID
+
+	rec = doRequest(e, http.MethodPost, base+"/items", `{"product_id":"A","quantity":2}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	doRequestIfMatch := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := doRequest(e, http.MethodGet, base, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("If-Match", rec.Header().Get("ETag"))
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	reserved := func() int64 {
+		rec := doRequest(e, http.MethodGet, "/v1/inventory/A", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var stock stockResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stock))
+		return stock.Reserved
+	}
+
+	// Changes that lose a race undo what they did outside the cart
+	cartRepo.races = 1
+	rec = doRequestIfMatch(http.MethodPost, base+"/items", `{"product_id":"A","quantity":3}`)
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code, rec.Body.String())
+	assert.Equal(t, int64(2), reserved())
+
+	cartRepo.races = 1
+	rec = doRequestIfMatch(http.MethodDelete, base+"/items", "")
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code, rec.Body.String())
+	assert.Equal(t, int64(2), reserved())
+
+	cartRepo.races = 1
+	rec = doRequestIfMatch(http.MethodPost, base+"/coupons", `{"code":"ONCE"}`)
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code, rec.Body.String())
+
+	// Without If-Match they are applied again to the newer cart
+	cartRepo.races = 2
+	rec = doRequest(e, http.MethodPost, base+"/coupons", `{"code":"ONCE"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	cartRepo.races = 2
+	rec = doRequest(e, http.MethodPost, base+"/items", `{"product_id":"A","quantity":1}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp cartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, int64(3), resp.Items[0].Quantity)
+	assert.Equal(t, "270.00", resp.Total)
+	assert.Equal(t, int64(3), reserved())
+
+	// A retry that fails gives back what the raced attempt reserved
+	cartRepo.races = 1
+	cartRepo.onRace = func() {
+		rec := doRequest(e, http.MethodPut, "/v1/inventory/A", `{"on_hand":4}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+	rec = doRequest(e, http.MethodPost, base+"/items", `{"product_id":"A","quantity":2}`)
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	var errResp errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, "A", errResp.SKU)
+	assert.Equal(t, int64(3), reserved())
+}
+
+func TestCartHandler_CreateCart(t *testing.T) {
+	e := newTestServer(t)
+	cartID := createTestCart(t, e, "user123")
+
+	rec := doRequest(e, http.MethodPost, "/v1/carts/", `{"user_id":"user123"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp cartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, cartID, resp.ID)
+
+	rec = doRequest(e, http.MethodPost, "/v1/carts/", `{"user_id":""}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCartHandler_Flow(t *testing.T) {
+	e := newTestServer(t)
+	createTestProduct(t, e, `{"id":"A","price":"100.00","discount":10}`)
+	createTestProduct(t, e, `{"id":"B","price":"50.00"}`)
+	cartID := createTestCart(t, e, "user123")
+	base := "/v1/carts/" + cartID
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      string
+		wantCode  int
+		wantTotal string
+	}{
+		{
+			name:      "add item",
+			method:    http.MethodPost,
+			path:      base + "/items",
+			body:      `{"product_id":"A","price":"1.00","quantity":3}`, // the catalog price wins
+			wantCode:  http.StatusOK,
+			wantTotal: "270.00",
+		},
+		{
+			name:      "add second item",
+			method:    http.MethodPost,
+			path:      base + "/items",
+			body:      `{"product_id":"B","quantity":1}`,
+			wantCode:  http.StatusOK,
+			wantTotal: "320.00",
+		},
+		{
+			name:     "add invalid quantity",
+			method:   http.MethodPost,
+			path:     base + "/items",
+			body:     `{"product_id":"B","quantity":0}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "add product missing from catalog",
+			method:   http.MethodPost,
+			path:     base + "/items",
+			body:     `{"product_id":"Z","quantity":1}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:      "update quantity",
+			method:    http.MethodPut,
+			path:      base + "/items/A",
+			body:      `{"quantity":2}`,
+			wantCode:  http.StatusOK,
+			wantTotal: "230.00",
+		},
+		{
+			name:     "update unknown item",
+			method:   http.MethodPut,
+			path:     base + "/items/Z",
+			body:     `{"quantity":2}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:      "apply total discount",
+			method:    http.MethodPost,
+			path:      base + "/promotions",
+			body:      `{"id":"P1","type":"totalDiscount","discount":10}`,
+			wantCode:  http.StatusOK,
+			wantTotal: "207.00",
+		},
+		{
+			name:     "apply unknown promotion type",
+			method:   http.MethodPost,
+			path:     base + "/promotions",
+			body:     `{"id":"P2","product_id":"A","type":"unknown"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:      "remove promotion",
+			method:    http.MethodDelete,
+			path:      base + "/promotions/P1",
+			wantCode:  http.StatusOK,
+			wantTotal: "230.00",
+		},
+		{
+			name:     "remove unknown promotion",
+			method:   http.MethodDelete,
+			path:     base + "/promotions/P1",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:      "remove item",
+			method:    http.MethodDelete,
+			path:      base + "/items/B",
+			wantCode:  http.StatusOK,
+			wantTotal: "180.00",
+		},
+		{
+			name:      "get total",
+			method:    http.MethodGet,
+			path:      base + "/total",
+			wantCode:  http.StatusOK,
+			wantTotal: "180.00",
+		},
+		{
+			name:      "get cart",
+			method:    http.MethodGet,
+			path:      base,
+			wantCode:  http.StatusOK,
+			wantTotal: "180.00",
+		},
+		{
+			name:      "get cart by user",
+			method:    http.MethodGet,
+			path:      "/v1/carts/users/user123",
+			wantCode:  http.StatusOK,
+			wantTotal: "180.00",
+		},
+		{
+			name:      "clear items",
+			method:    http.MethodDelete,
+			path:      base + "/items",
+			wantCode:  http.StatusOK,
+			wantTotal: "0.00",
+		},
+		{
+			name:     "get unknown cart",
+			method:   http.MethodGet,
+			path:     "/v1/carts/unknown",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(e, tt.method, tt.path, tt.body)
+			require.Equal(t, tt.wantCode, rec.Code, rec.Body.String())
+
+			if tt.wantTotal != "" {
+				var resp struct {
+					Total string `json:"total"`
+				}
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, tt.wantTotal, resp.Total)
+			}
+		})
+	}
+}
+
+func TestCartHandler_ApplyCoupon(t *testing.T) {
+	e := newTestServer(t)
+	cartID := createTestCart(t, e, "user123")
+	base := "/v1/carts/" + cartID
+
+	rec := doRequest(e, http.MethodPost, "/v1/coupons/", `{"code":"save10","max_redemptions_per_user":1,"promotion":{"type":"totalDiscount","discount":10}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPost, "/v1/coupons/", `{"code":"BIG","min_spend":"1000","promotion":{"type":"fixedCartDiscount","amount":"100"}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPost, "/v1/coupons/", `{"code":"SAVE10","promotion":{"type":"totalDiscount","discount":10}}`)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	rec = doRequest(e, http.MethodPost, "/v1/coupons/", `{"code":"BAD","promotion":{"type":"unknown"}}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	createTestProduct(t, e, `{"id":"A","price":"100.00"}`)
+	rec = doRequest(e, http.MethodPost, base+"/items", `{"product_id":"A","quantity":2}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantCode   int
+		wantReason string
+		wantTotal  string
+	}{
+		{
+			name:      "apply coupon",
+			body:      `{"code":"Save10"}`,
+			wantCode:  http.StatusOK,
+			wantTotal: "180.00",
+		},
+		{
+			name:       "apply twice",
+			body:       `{"code":"SAVE10"}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantReason: "alreadyApplied",
+		},
+		{
+			name:       "minimum not met",
+			body:       `{"code":"BIG"}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantReason: "minimumNotMet",
+		},
+		{
+			name:     "unknown code",
+			body:     `{"code":"NOPE"}`,
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(e, http.MethodPost, base+"/coupons", tt.body)
+			require.Equal(t, tt.wantCode, rec.Code, rec.Body.String())
+
+			var resp struct {
+				Total  string `json:"total"`
+				Reason string `json:"reason"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.wantReason, resp.Reason)
+			if tt.wantTotal != "" {
+				assert.Equal(t, tt.wantTotal, resp.Total)
+			}
+		})
+	}
+
+	// The per-user limit holds after the promotion is removed
+	rec = doRequest(e, http.MethodDelete, base+"/promotions/coupon:SAVE10", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(e, http.MethodPost, base+"/coupons", `{"code":"SAVE10"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "exhausted")
+
+	// Naming another user does not reset the limit, redemptions count the cart's owner
+	rec = doRequest(e, http.MethodPost, base+"/coupons", `{"code":"SAVE10","user_id":"someone_else"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "exhausted")
+
+	otherCartID := createTestCart(t, e, "user456")
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+otherCartID+"/items", `{"product_id":"A","quantity":1}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+otherCartID+"/coupons", `{"code":"SAVE10"}`)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestCartHandler_SetTax(t *testing.T) {
+	e := newTestServer(t)
+	cartID := createTestCart(t, e, "user123")
+	base := "/v1/carts/" + cartID
+
+	createTestProduct(t, e, `{"id":"A","price":"107.00"}`)
+	rec := doRequest(e, http.MethodPost, base+"/items", `{"product_id":"A","quantity":1}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(e, http.MethodPut, base+"/tax", `{"region":"XX"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodPut, base+"/tax", `{"region":"TH"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var cartResp cartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &cartResp))
+	assert.Equal(t, "TH", cartResp.TaxRegion)
+
+	rec = doRequest(e, http.MethodGet, base+"/total", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp totalResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Taxes, 1)
+	assert.Equal(t, "100.00", resp.Taxes[0].Net)
+	assert.Equal(t, "7.00", resp.Tax)
+	assert.Equal(t, "107.00", resp.Total)
+}
+
+func TestShippingHandler_GetQuote(t *testing.T) {
+	e := newTestServer(t)
+	cartID := createTestCart(t, e, "user123")
+	base := "/v1/carts/" + cartID
+
+	createTestProduct(t, e, `{"id":"A","price":"300.00","weight":"1.5"}`)
+	rec := doRequest(e, http.MethodPost, base+"/items", `{"product_id":"A","quantity":2}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, base+"/shipping?country=TH", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp shippingResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "domestic", resp.Zone)
+	assert.Equal(t, "80.00", resp.Total)
+
+	rec = doRequest(e, http.MethodPut, base+"/items/A", `{"quantity":4}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(e, http.MethodGet, base+"/shipping?country=TH", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "150.00", resp.Cost)
+	assert.Equal(t, "0.00", resp.Total)
+	assert.Equal(t, "free-domestic", resp.FreeShippingID)
+
+	rec = doRequest(e, http.MethodGet, base+"/shipping", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = doRequest(e, http.MethodGet, "/v1/carts/unknown/shipping?country=TH", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestShippingHandler_GetQuote_Currency(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		price    string
+		rate     string
+		wantCost string
+	}{
+		{name: "no minor unit", currency: "JPY", price: "1000", rate: "800", wantCost: "800"},
+		{name: "three digits", currency: "KWD", price: "10", rate: "1.5", wantCost: "1.500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cartRepo := repository.NewCartRepository()
+			calc := shipping.Calculator{Zones: []shipping.Zone{{
+				Name:  "everywhere",
+				Table: shipping.FlatRate{Amount: cart.NewMoney(decimal.RequireFromString(tt.rate), tt.currency)},
+			}}}
+			e := echo.New()
+			RegisterShippingHandler(e.Group("/v1/carts"), service.NewShippingService(cartRepo, calc))
+
+			ctx := context.Background()
+			cartID, err := cartRepo.Create(ctx, "user123")
+			require.NoError(t, err)
+			c := cart.NewCart()
+			require.NoError(t, c.AddProduct(cart.Product{ID: "A", Price: decimal.RequireFromString(tt.price), Currency: tt.currency}, 1))
+			require.NoError(t, cartRepo.Update(ctx, cartID, c))
+
+			rec := doRequest(e, http.MethodGet, "/v1/carts/"+cartID+"/shipping?country=TH", "")
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			var resp shippingResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.currency, resp.Currency)
+			assert.Equal(t, tt.wantCost, resp.Cost)
+			assert.Equal(t, tt.wantCost, resp.Total)
+		})
+	}
+
+	// The default rates are in baht
+	e := newTestServer(t)
+	createTestProduct(t, e, `{"id":"A","price":"1000","currency":"JPY"}`)
+	cartID := createTestCart(t, e, "user123")
+	rec := doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"A","quantity":1}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodGet, "/v1/carts/"+cartID+"/shipping?country=TH", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+}
+
+func TestCartHandler_Currency(t *testing.T) {
+	e := newTestServer(t)
+	cartID := createTestCart(t, e, "user123")
+	base := "/v1/carts/" + cartID
+
+	createTestProduct(t, e, `{"id":"A","price":"1000","currency":"JPY"}`)
+	rec := doRequest(e, http.MethodPost, base+"/items", `{"product_id":"A","quantity":2}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp cartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "JPY", resp.Currency)
+	assert.Equal(t, "2000", resp.Total)
+
+	createTestProduct(t, e, `{"id":"B","price":"10.00","currency":"USD"}`)
+	rec = doRequest(e, http.MethodPost, base+"/items", `{"product_id":"B","quantity":1}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestQuoteHandler_GetQuote(t *testing.T) {
+	e := newTestServer(t)
+	cartID := createTestCart(t, e, "user123")
+	base := "/v1/carts/" + cartID
+
+	createTestProduct(t, e, `{"id":"A","price":"333.33","currency":"THB"}`)
+	rec := doRequest(e, http.MethodPost, base+"/items", `{"product_id":"A","quantity":1}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, base+"/quote?currency=USD", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp quoteResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "USD", resp.Currency)
+	assert.Equal(t, "0.03", resp.Rate)
+	require.NotNil(t, resp.RateAsOf)
+	assert.Equal(t, "10.00", resp.Total)
+	require.Len(t, resp.Lines, 1)
+	assert.Equal(t, "10.00", resp.Lines[0].LineTotal)
+	assert.Equal(t, "THB", resp.ChargeCurrency)
+	assert.Equal(t, "333.33", resp.ChargeTotal)
+	assert.Empty(t, resp.Bundles)
+
+	// Bundles are quoted beside the lines
+	createTestProduct(t, e, `{"id":"B","price":"166.67","currency":"THB"}`)
+	rec = doRequest(e, http.MethodPost, base+"/items", `{"product_id":"B","quantity":1}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPost, base+"/promotions", `{"id":"KIT","type":"bundlePrice","amount":"400","bundle_items":[{"product_id":"A","quantity":1},{"product_id":"B","quantity":1}]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodGet, base+"/quote?currency=USD", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	resp = quoteResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Bundles, 1)
+	assert.Equal(t, "KIT", resp.Bundles[0].PromotionID)
+	assert.Equal(t, int64(1), resp.Bundles[0].Count)
+	assert.Equal(t, "12.00", resp.Bundles[0].Total)
+	assert.Equal(t, "12.00", resp.Subtotal)
+	assert.Equal(t, "400.00", resp.ChargeTotal)
+
+	rec = doRequest(e, http.MethodGet, base+"/quote?currency=EUR", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	rec = doRequest(e, http.MethodGet, base+"/quote?currency=XXX", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = doRequest(e, http.MethodGet, "/v1/carts/unknown/quote?currency=USD", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestCatalogHandler(t *testing.T) {
+	e := newTestServer(t)
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      string
+		wantCode  int
+		wantPrice string
+	}{
+		{
+			name:      "create product",
+			method:    http.MethodPost,
+			path:      "/v1/products/",
+			body:      `{"id":"A","description":"Coffee","price":"120.00","currency":"thb"}`,
+			wantCode:  http.StatusCreated,
+			wantPrice: "120.00",
+		},
+		{
+			name:     "create duplicate product",
+			method:   http.MethodPost,
+			path:     "/v1/products/",
+			body:     `{"id":"A","price":"1.00"}`,
+			wantCode: http.StatusConflict,
+		},
+		{
+			name:     "create invalid product",
+			method:   http.MethodPost,
+			path:     "/v1/products/",
+			body:     `{"id":"B","price":"-1.00"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:      "update product",
+			method:    http.MethodPut,
+			path:      "/v1/products/A",
+			body:      `{"price":"99.50","currency":"THB"}`,
+			wantCode:  http.StatusOK,
+			wantPrice: "99.50",
+		},
+		{
+			name:     "update unknown product",
+			method:   http.MethodPut,
+			path:     "/v1/products/Z",
+			body:     `{"price":"1.00"}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:      "get product",
+			method:    http.MethodGet,
+			path:      "/v1/products/A",
+			wantCode:  http.StatusOK,
+			wantPrice: "99.50",
+		},
+		{
+			name:     "delete product",
+			method:   http.MethodDelete,
+			path:     "/v1/products/A",
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "get deleted product",
+			method:   http.MethodGet,
+			path:     "/v1/products/A",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(e, tt.method, tt.path, tt.body)
+			require.Equal(t, tt.wantCode, rec.Code, rec.Body.String())
+
+			if tt.wantPrice != "" {
+				var resp productResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, "A", resp.ID)
+				assert.Equal(t, "THB", resp.Currency)
+				assert.Equal(t, tt.wantPrice, resp.Price)
+			}
+		})
+	}
+}
+
+func TestCartHandler_Variants(t *testing.T) {
+	e := newTestServer(t)
+	createTestProduct(t, e, `{"id":"SHIRT","price":"500.00","variants":[{"sku":"SHIRT-S","options":{"size":"S"},"stock":5},{"sku":"SHIRT-M","options":{"size":"M"},"price":"550.00","stock":5}]}`)
+	cartID := createTestCart(t, e, "user123")
+	base := "/v1/carts/" + cartID
+
+	rec := doRequest(e, http.MethodPost, base+"/items", `{"product_id":"SHIRT","quantity":1}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = doRequest(e, http.MethodPost, base+"/items", `{"product_id":"SHIRT","sku":"SHIRT-XL","quantity":1}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, base+"/items", `{"product_id":"SHIRT","sku":"SHIRT-S","quantity":1}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPost, base+"/items", `{"product_id":"SHIRT","sku":"SHIRT-M","quantity":1}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPost, base+"/promotions", `{"id":"P1","product_id":"SHIRT","sku":"SHIRT-M","type":"percentageDiscount","discount":10}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp cartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 2)
+	assert.Equal(t, "SHIRT-M", resp.Items[0].SKU)
+	assert.Equal(t, map[string]string{"size": "M"}, resp.Items[0].Options)
+	assert.Equal(t, "550.00", resp.Items[0].Price)
+	assert.Equal(t, "SHIRT-M", resp.Promotions[0].SKU)
+	assert.Equal(t, "995.00", resp.Total) // 495 + 500
+
+	rec = doRequest(e, http.MethodPut, base+"/items/SHIRT-S", `{"quantity":2}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodDelete, base+"/items/SHIRT-M", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	resp = cartResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, "1000.00", resp.Total)
+}
+
+func TestCartHandler_VariantStock(t *testing.T) {
+	e := newTestServer(t)
+	createTestProduct(t, e, `{"id":"SHIRT","price":"500.00","variants":[{"sku":"SHIRT-S","stock":5},{"sku":"SHIRT-M"}]}`)
+
+	rec := doRequest(e, http.MethodGet, "/v1/inventory/SHIRT-M", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stock stockResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stock))
+	assert.False(t, stock.Tracked, "a variant created without stock is untracked")
+
+	rec = doRequest(e, http.MethodPut, "/v1/inventory/SHIRT-S", `{"on_hand":8}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	cartID := createTestCart(t, e, "user123")
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"SHIRT","sku":"SHIRT-S","quantity":2}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"SHIRT","sku":"SHIRT-M","quantity":10}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// An update leaves the stock set through the inventory routes alone
+	rec = doRequest(e, http.MethodPut, "/v1/products/SHIRT", `{"price":"450.00","variants":[{"sku":"SHIRT-S","stock":1},{"sku":"SHIRT-M"}]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var product productResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &product))
+	require.Len(t, product.Variants, 2)
+	require.NotNil(t, product.Variants[0].Stock)
+	assert.Equal(t, int64(8), *product.Variants[0].Stock)
+	require.NotNil(t, product.Variants[0].Available)
+	assert.Equal(t, int64(6), *product.Variants[0].Available)
+	assert.Nil(t, product.Variants[1].Stock)
+
+	rec = doRequest(e, http.MethodGet, "/v1/products/", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var products []productResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &products))
+	require.Len(t, products, 1)
+	assert.Equal(t, product.Variants, products[0].Variants)
+}
+
+func TestCartHandler_Inventory(t *testing.T) {
+	e := newTestServer(t)
+	createTestProduct(t, e, `{"id":"A","price":"100.00"}`)
+	rec := doRequest(e, http.MethodPut, "/v1/inventory/A", `{"on_hand":3}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPut, "/v1/inventory/A", `{"on_hand":-1}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	first := createTestCart(t, e, "user1")
+	second := createTestCart(t, e, "user2")
+
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+first+"/items", `{"product_id":"A","quantity":2}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+second+"/items", `{"product_id":"A","quantity":2}`)
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	var errResp errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, "A", errResp.SKU)
+	require.NotNil(t, errResp.Available)
+	assert.Equal(t, int64(1), *errResp.Available)
+
+	// A refused update leaves the line and its reservation as they were
+	rec = doRequest(e, http.MethodPut, "/v1/carts/"+first+"/items/A", `{"quantity":4}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = doRequest(e, http.MethodGet, "/v1/inventory/A", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stock stockResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stock))
+	assert.Equal(t, stockResponse{SKU: "A", Tracked: true, OnHand: 3, Reserved: 2, Available: 1}, stock)
+
+	rec = doRequest(e, http.MethodDelete, "/v1/carts/"+first, "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	rec = doRequest(e, http.MethodDelete, "/v1/carts/"+first, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+second+"/items", `{"product_id":"A","quantity":3}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+// extendingStock counts the extensions of each cart's reservations
+type extendingStock struct {
+	inventory.Stock
+	extended map[string]int
+}
+
+func (s *extendingStock) Extend(ctx context.Context, cartID string, expiresAt time.Time) error {
+	s.extended[cartID]++
+	return s.Stock.Extend(ctx, cartID, expiresAt)
+}
+
+func TestCartHandler_ReservationsExtended(t *testing.T) {
+	stock := &extendingStock{Stock: repository.NewStockRepository(), extended: make(map[string]int)}
+	e := newTestServerWith(t, repository.NewCartRepository(), stock, repository.NewPurchaseHistoryRepository())
+	createTestProduct(t, e, `{"id":"A","price":"100.00"}`)
+	createTestProduct(t, e, `{"id":"B","price":"50.00"}`)
+	rec := doRequest(e, http.MethodPut, "/v1/inventory/A", `{"on_hand":3}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	cartID := createTestCart(t, e, "user1")
+
+	// Every stored change holds all of the cart's lines, not only the changed one
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"A","quantity":2}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"B","quantity":1}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, 2, stock.extended[cartID])
+
+	rec = doRequest(e, http.MethodPut, "/v1/carts/"+cartID+"/items/A", `{"quantity":4}`)
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	assert.Equal(t, 2, stock.extended[cartID])
+}
+
+func TestCartHandler_Limits(t *testing.T) {
+	e := newTestServer(t)
+	createTestProduct(t, e, `{"id":"A","price":"100.00"}`)
+	createTestProduct(t, e, `{"id":"LTD","price":"900.00","max_per_customer":1}`)
+	rec := doRequest(e, http.MethodPut, "/v1/inventory/A", `{"on_hand":20}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	cartID := createTestCart(t, e, "user1")
+
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"A","quantity":11}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	var errResp errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, cart.ViolationLineQuantity, errResp.Code)
+	assert.Equal(t, "A", errResp.SKU)
+	assert.Equal(t, "10", errResp.Limit)
+
+	// Nothing stays reserved for the refused line
+	rec = doRequest(e, http.MethodGet, "/v1/inventory/A", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stock stockResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stock))
+	assert.Equal(t, int64(0), stock.Reserved)
+
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"LTD","quantity":2}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, cart.ViolationCustomerLimit, errResp.Code)
+	assert.Equal(t, "1", errResp.Limit)
+
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"LTD","quantity":1}`)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestCartHandler_PurchaseHistory(t *testing.T) {
+	history := repository.NewPurchaseHistoryRepository()
+	require.NoError(t, history.Record(context.Background(), "user1", map[string]int64{"LTD": 1}))
+	e := newTestServerWith(t, repository.NewCartRepository(), repository.NewStockRepository(), history)
+	createTestProduct(t, e, `{"id":"LTD","price":"900.00","max_per_customer":2,"variants":[{"sku":"LTD-S"},{"sku":"LTD-M"}]}`)
+
+	// The limit counts what the customer bought before, not just this cart
+	cartID := createTestCart(t, e, "user1")
+	rec := doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"LTD","sku":"LTD-S","quantity":2}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	var errResp errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, cart.ViolationCustomerLimit, errResp.Code)
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"LTD","sku":"LTD-S","quantity":1}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// A purchase recorded while the cart is open counts on its next change
+	require.NoError(t, history.Record(context.Background(), "user1", map[string]int64{"LTD": 1}))
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"LTD","sku":"LTD-M","quantity":1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+
+	other := createTestCart(t, e, "user2")
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+other+"/items", `{"product_id":"LTD","sku":"LTD-S","quantity":2}`)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestPurchaseHandler_RecordPurchase(t *testing.T) {
+	e := newTestServer(t)
+	createTestProduct(t, e, `{"id":"LTD","price":"900.00","max_per_customer":2}`)
+	cartID := createTestCart(t, e, "user1")
+
+	rec := doRequest(e, http.MethodGet, "/v1/users/user1/purchases", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"user_id":"user1","purchases":{}}`, rec.Body.String())
+
+	for _, body := range []string{`{}`, `{"purchases":{"LTD":0}}`, `{"purchases":{"LTD":-1}}`} {
+		rec = doRequest(e, http.MethodPost, "/v1/users/user1/purchases", body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+	rec = doRequest(e, http.MethodPost, "/v1/users/user1/purchases", `{"purchases":{"NOPE":1}}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodPost, "/v1/users/user1/purchases", `{"purchases":{"LTD":1}}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPost, "/v1/users/user1/purchases", `{"purchases":{"LTD":1}}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"user_id":"user1","purchases":{"LTD":2}}`, rec.Body.String())
+
+	// The recorded purchases use up the customer's limit
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"LTD","quantity":1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodGet, "/v1/users/user2/purchases", "")
+	assert.JSONEq(t, `{"user_id":"user2","purchases":{}}`, rec.Body.String())
+}
+
+func TestCartHandler_Measured(t *testing.T) {
+	e := newTestServer(t)
+	createTestProduct(t, e, `{"id":"COFFEE","price":"800.00","unit":"kg","min_measure":"0.25","step":"0.05"}`)
+	cartID := createTestCart(t, e, "user1")
+
+	rec := doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"COFFEE","quantity":2}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"COFFEE","measure":"0.33"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"COFFEE","measure":"1.25"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp cartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, "1.25", resp.Items[0].Measure)
+	assert.Equal(t, cart.UnitKilogram, resp.Items[0].Unit)
+	assert.Equal(t, "1000.00", resp.Total)
+
+	rec = doRequest(e, http.MethodPut, "/v1/carts/"+cartID+"/items/COFFEE", `{"measure":"0.5"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "400.00", resp.Total)
+
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/promotions", `{"id":"BOGO","product_id":"COFFEE","type":"buy1Get1Free"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/v1/carts/"+cartID+"/total", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var total totalResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &total))
+	require.Len(t, total.Lines, 1)
+	assert.Equal(t, "0.5", total.Lines[0].Measure)
+	assert.Equal(t, "800.00", total.Lines[0].UnitPrice)
+	assert.Equal(t, "400.00", total.Lines[0].LineTotal)
+}
+
+func TestCartHandler_ETag(t *testing.T) {
+	e := newTestServer(t)
+	createTestProduct(t, e, `{"id":"A","price":"100.00"}`)
+	rec := doRequest(e, http.MethodPut, "/v1/inventory/A", `{"on_hand":20}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	cartID := createTestCart(t, e, "user1")
+
+	addItem := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/carts/"+cartID+"/items", strings.NewReader(`{"product_id":"A","quantity":1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = doRequest(e, http.MethodGet, "/v1/carts/"+cartID, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+
+	rec = addItem(`"1"`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	// The cart changed since version 1 was read
+	rec = addItem(`"1"`)
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code, rec.Body.String())
+	assert.Empty(t, rec.Header().Get("ETag"))
+	var errResp errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, int64(2), errResp.Version)
+
+	// Nothing stays reserved for the refused change
+	rec = doRequest(e, http.MethodGet, "/v1/inventory/A", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stock stockResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stock))
+	assert.Equal(t, int64(1), stock.Reserved)
+
+	tests := []struct {
+		name     string
+		ifMatch  string
+		wantCode int
+		wantETag string
+	}{
+		{name: "no If-Match", ifMatch: "", wantCode: http.StatusOK, wantETag: `"3"`},
+		{name: "any version", ifMatch: "*", wantCode: http.StatusOK, wantETag: `"4"`},
+		{name: "current version", ifMatch: `"4"`, wantCode: http.StatusOK, wantETag: `"5"`},
+		{name: "weak tag", ifMatch: `W/"5"`, wantCode: http.StatusPreconditionFailed},
+		{name: "unquoted", ifMatch: "5", wantCode: http.StatusPreconditionFailed},
+		{name: "not a version", ifMatch: `"abc"`, wantCode: http.StatusPreconditionFailed},
+		{name: "version 0", ifMatch: `"0"`, wantCode: http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := addItem(tt.ifMatch)
+			assert.Equal(t, tt.wantCode, rec.Code, rec.Body.String())
+			assert.Equal(t, tt.wantETag, rec.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestCartHandler_GetCartByUserID_ETag(t *testing.T) {
+	e := newTestServer(t)
+	createTestProduct(t, e, `{"id":"A","price":"100.00"}`)
+	cartID := createTestCart(t, e, "user1")
+	rec := doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"A","quantity":1}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/v1/carts/users/user1", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	var resp cartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, cartID, resp.ID)
+
+	// The tag read by user guards a change like one read by cart ID
+	req := httptest.NewRequest(http.MethodPost, "/v1/carts/"+resp.ID+"/items", strings.NewReader(`{"product_id":"A","quantity":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("If-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+
+	rec = doRequest(e, http.MethodGet, "/v1/carts/users/unknown", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, rec.Header().Get("ETag"))
+}
+
+func TestCartHandler_DeleteCart_IfMatch(t *testing.T) {
+	e := newTestServer(t)
+	createTestProduct(t, e, `{"id":"A","price":"100.00"}`)
+	rec := doRequest(e, http.MethodPut, "/v1/inventory/A", `{"on_hand":5}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	cartID := createTestCart(t, e, "user1")
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"A","quantity":2}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	deleteCart := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/v1/carts/"+cartID, nil)
+		req.Header.Set("If-Match", ifMatch)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = deleteCart(`"1"`)
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code, rec.Body.String())
+	var errResp errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, int64(2), errResp.Version)
+	rec = deleteCart("abc")
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code, rec.Body.String())
+
+	// The refused deletes kept the cart and its reservation
+	rec = doRequest(e, http.MethodGet, "/v1/inventory/A", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stock stockResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stock))
+	assert.Equal(t, int64(2), stock.Reserved)
+
+	rec = deleteCart(`"2"`)
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodGet, "/v1/carts/"+cartID, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestCartHandler_GetTotal_ETag(t *testing.T) {
+	e := newTestServer(t)
+	createTestProduct(t, e, `{"id":"A","price":"100.00"}`)
+	cartID := createTestCart(t, e, "user1")
+
+	rec := doRequest(e, http.MethodGet, "/v1/carts/"+cartID+"/total", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+
+	rec = doRequest(e, http.MethodPost, "/v1/carts/"+cartID+"/items", `{"product_id":"A","quantity":2}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodGet, "/v1/carts/"+cartID+"/total", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	var resp totalResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "200.00", resp.Total)
+
+	rec = doRequest(e, http.MethodGet, "/v1/carts/unknown/total", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, rec.Header().Get("ETag"))
+}
@@ -0,0 +1,242 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkittipat/try-cart/internal/app/service"
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/pkittipat/try-cart/internal/domain/inventory"
+	"github.com/shopspring/decimal"
+)
+
+type catalogHandler struct {
+	catalogSrv *service.CatalogService
+}
+
+type (
+	productRequest struct {
+		ID             string             `json:"id"`
+		Description    string             `json:"description"`
+		Price          decimal.Decimal    `json:"price"`
+		Currency       string             `json:"currency"`
+		Discount       int64              `json:"discount"`
+		PriceTiers     []priceTierPayload `json:"price_tiers"`
+		TierMode       cart.TierMode      `json:"tier_mode"`
+		TaxCategory    cart.TaxCategory   `json:"tax_category"`
+		MaxPerCustomer int64              `json:"max_per_customer"`
+		Unit           cart.UnitOfMeasure `json:"unit"`
+		MinMeasure     decimal.Decimal    `json:"min_measure"`
+		Step           decimal.Decimal    `json:"step"`
+		Weight         decimal.Decimal    `json:"weight"`
+		Length         decimal.Decimal    `json:"length"`
+		Width          decimal.Decimal    `json:"width"`
+		Height         decimal.Decimal    `json:"height"`
+		Variants       []variantPayload   `json:"variants"`
+	}
+
+	variantPayload struct {
+		SKU     string            `json:"sku"`
+		Options map[string]string `json:"options,omitempty"`
+		Price   *decimal.Decimal  `json:"price,omitempty"` // omitted keeps the product price
+		Stock   *int64            `json:"stock,omitempty"` // units on hand, only read when the product is created
+	}
+
+	variantResponse struct {
+		SKU       string            `json:"sku"`
+		Options   map[string]string `json:"options,omitempty"`
+		Price     *decimal.Decimal  `json:"price,omitempty"`
+		Stock     *int64            `json:"stock,omitempty"`     // units on hand, omitted when untracked
+		Available *int64            `json:"available,omitempty"` // on hand less reservations
+	}
+
+	priceTierPayload struct {
+		MinQuantity int64           `json:"min_quantity"`
+		UnitPrice   decimal.Decimal `json:"unit_price"`
+	}
+
+	productResponse struct {
+		ID             string             `json:"id"`
+		Description    string             `json:"description"`
+		Price          string             `json:"price"`
+		Currency       string             `json:"currency,omitempty"`
+		Discount       int64              `json:"discount"`
+		PriceTiers     []priceTierPayload `json:"price_tiers,omitempty"`
+		TierMode       cart.TierMode      `json:"tier_mode,omitempty"`
+		TaxCategory    cart.TaxCategory   `json:"tax_category,omitempty"`
+		MaxPerCustomer int64              `json:"max_per_customer,omitempty"`
+		Unit           cart.UnitOfMeasure `json:"unit,omitempty"`
+		MinMeasure     string             `json:"min_measure,omitempty"`
+		Step           string             `json:"step,omitempty"`
+		Weight         string             `json:"weight"`
+		Length         string             `json:"length"`
+		Width          string             `json:"width"`
+		Height         string             `json:"height"`
+		Variants       []variantResponse  `json:"variants,omitempty"`
+	}
+)
+
+// RegisterCatalogHandler adds the product catalog routes
+func RegisterCatalogHandler(
+	router *echo.Group,
+	catalogSrv *service.CatalogService,
+) {
+	handler := catalogHandler{
+		catalogSrv: catalogSrv,
+	}
+
+	router.POST("/", handler.CreateProduct)
+	router.GET("/", handler.ListProducts)
+	router.GET("/:productID", handler.GetProduct)
+	router.PUT("/:productID", handler.UpdateProduct)
+	router.DELETE("/:productID", handler.DeleteProduct)
+}
+
+func (h *catalogHandler) CreateProduct(e echo.Context) error {
+	var req productRequest
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(http.StatusBadRequest, errorResponse{Message: "invalid request body"})
+	}
+
+	product := req.toProduct()
+	if err := h.catalogSrv.CreateProduct(e.Request().Context(), product); err != nil {
+		return errorJSON(e, err)
+	}
+
+	return h.getProduct(e, http.StatusCreated, product.ID)
+}
+
+func (h *catalogHandler) ListProducts(e echo.Context) error {
+	products, err := h.catalogSrv.ListProducts(e.Request().Context())
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	resp := make([]productResponse, 0, len(products))
+	for _, product := range products {
+		levels, err := h.catalogSrv.VariantStock(e.Request().Context(), product)
+		if err != nil {
+			return errorJSON(e, err)
+		}
+		resp = append(resp, newProductResponse(product, levels))
+	}
+	return e.JSON(http.StatusOK, resp)
+}
+
+func (h *catalogHandler) GetProduct(e echo.Context) error {
+	return h.getProduct(e, http.StatusOK, e.Param("productID"))
+}
+
+// UpdateProduct replaces the product named in the path, the body's id is ignored
+func (h *catalogHandler) UpdateProduct(e echo.Context) error {
+	var req productRequest
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(http.StatusBadRequest, errorResponse{Message: "invalid request body"})
+	}
+
+	product := req.toProduct()
+	product.ID = e.Param("productID")
+	if err := h.catalogSrv.UpdateProduct(e.Request().Context(), product); err != nil {
+		return errorJSON(e, err)
+	}
+
+	return h.getProduct(e, http.StatusOK, product.ID)
+}
+
+func (h *catalogHandler) DeleteProduct(e echo.Context) error {
+	if err := h.catalogSrv.DeleteProduct(e.Request().Context(), e.Param("productID")); err != nil {
+		return errorJSON(e, err)
+	}
+
+	return e.NoContent(http.StatusNoContent)
+}
+
+func (h *catalogHandler) getProduct(e echo.Context, status int, productID string) error {
+	ctx := e.Request().Context()
+	product, err := h.catalogSrv.GetProduct(ctx, productID)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+	levels, err := h.catalogSrv.VariantStock(ctx, product)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return e.JSON(status, newProductResponse(product, levels))
+}
+
+func (r productRequest) toProduct() cart.Product {
+	product := cart.Product{
+		ID:             r.ID,
+		Description:    r.Description,
+		Price:          r.Price,
+		Currency:       r.Currency,
+		Discount:       r.Discount,
+		TierMode:       r.TierMode,
+		TaxCategory:    r.TaxCategory,
+		MaxPerCustomer: r.MaxPerCustomer,
+		Unit:           r.Unit,
+		MinMeasure:     r.MinMeasure,
+		Step:           r.Step,
+		Weight:         r.Weight,
+		Length:         r.Length,
+		Width:          r.Width,
+		Height:         r.Height,
+	}
+	for _, tier := range r.PriceTiers {
+		product.PriceTiers = append(product.PriceTiers, cart.PriceTier{
+			MinQuantity: tier.MinQuantity,
+			UnitPrice:   tier.UnitPrice,
+		})
+	}
+	for _, variant := range r.Variants {
+		product.Variants = append(product.Variants, cart.Variant{
+			SKU:     variant.SKU,
+			Options: variant.Options,
+			Price:   variant.Price,
+			Stock:   variant.Stock,
+		})
+	}
+	return product
+}
+
+// newProductResponse shows the stock of the variants from levels, keyed by SKU
+func newProductResponse(product cart.Product, levels map[string]inventory.Level) productResponse {
+	resp := productResponse{
+		ID:             product.ID,
+		Description:    product.Description,
+		Price:          cart.DisplayAmount(product.Price, product.Currency),
+		Currency:       product.Currency,
+		Discount:       product.Discount,
+		TierMode:       product.TierMode,
+		TaxCategory:    product.TaxCategory,
+		MaxPerCustomer: product.MaxPerCustomer,
+		Unit:           product.Unit,
+		MinMeasure:     displayOptionalMeasure(product.MinMeasure),
+		Step:           displayOptionalMeasure(product.Step),
+		Weight:         product.Weight.String(),
+		Length:         product.Length.String(),
+		Width:          product.Width.String(),
+		Height:         product.Height.String(),
+	}
+	for _, tier := range product.PriceTiers {
+		resp.PriceTiers = append(resp.PriceTiers, priceTierPayload{
+			MinQuantity: tier.MinQuantity,
+			UnitPrice:   tier.UnitPrice,
+		})
+	}
+	for _, variant := range product.Variants {
+		variantResp := variantResponse{
+			SKU:     variant.SKU,
+			Options: variant.Options,
+			Price:   variant.Price,
+		}
+		if level, ok := levels[variant.SKU]; ok {
+			onHand, available := level.OnHand, level.Available()
+			variantResp.Stock = &onHand
+			variantResp.Available = &available
+		}
+		resp.Variants = append(resp.Variants, variantResp)
+	}
+	return resp
+}
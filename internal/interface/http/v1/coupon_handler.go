@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkittipat/try-cart/internal/app/service"
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/shopspring/decimal"
+)
+
+type couponHandler struct {
+	cartSrv *service.CartService
+}
+
+type (
+	createCouponRequest struct {
+		Code      string           `json:"code"`
+		Promotion promotionRequest `json:"promotion"`
+
+		ValidFrom             time.Time       `json:"valid_from"`
+		ValidUntil            time.Time       `json:"valid_until"`
+		MinSpend              decimal.Decimal `json:"min_spend"`
+		MaxRedemptions        int64           `json:"max_redemptions"`
+		MaxRedemptionsPerUser int64           `json:"max_redemptions_per_user"`
+		SingleUse             bool            `json:"single_use"`
+	}
+
+	couponResponse struct {
+		Code string `json:"code"`
+	}
+)
+
+func RegisterCouponHandler(
+	router *echo.Group,
+	cartSrv *service.CartService,
+) {
+	handler := couponHandler{
+		cartSrv: cartSrv,
+	}
+
+	router.POST("/", handler.CreateCoupon)
+}
+
+func (h *couponHandler) CreateCoupon(e echo.Context) error {
+	var req createCouponRequest
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(http.StatusBadRequest, errorResponse{Message: "invalid request body"})
+	}
+
+	coupon := cart.Coupon{
+		Code:      req.Code,
+		Promotion: req.Promotion.toPromotion(),
+
+		ValidFrom:             req.ValidFrom,
+		ValidUntil:            req.ValidUntil,
+		MinSpend:              req.MinSpend,
+		MaxRedemptions:        req.MaxRedemptions,
+		MaxRedemptionsPerUser: req.MaxRedemptionsPerUser,
+		SingleUse:             req.SingleUse,
+	}
+	if err := h.cartSrv.CreateCoupon(e.Request().Context(), coupon); err != nil {
+		return errorJSON(e, err)
+	}
+
+	return e.JSON(http.StatusCreated, couponResponse{Code: cart.NormalizeCouponCode(req.Code)})
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkittipat/try-cart/internal/app/service"
+	"github.com/pkittipat/try-cart/internal/domain/inventory"
+)
+
+type inventoryHandler struct {
+	catalogSrv *service.CatalogService
+}
+
+type (
+	setStockRequest struct {
+		OnHand int64 `json:"on_hand"`
+	}
+
+	stockResponse struct {
+		SKU       string `json:"sku"`
+		Tracked   bool   `json:"tracked"`
+		OnHand    int64  `json:"on_hand"`
+		Reserved  int64  `json:"reserved"`
+		Available int64  `json:"available"`
+	}
+)
+
+// RegisterInventoryHandler adds the stock routes, keyed by SKU
+func RegisterInventoryHandler(
+	router *echo.Group,
+	catalogSrv *service.CatalogService,
+) {
+	handler := inventoryHandler{
+		catalogSrv: catalogSrv,
+	}
+
+	router.GET("/:sku", handler.GetStock)
+	router.PUT("/:sku", handler.SetStock)
+}
+
+// GetStock returns the stock of the SKU. Untracked SKUs are reported with tracked false.
+func (h *inventoryHandler) GetStock(e echo.Context) error {
+	sku := e.Param("sku")
+	level, ok, err := h.catalogSrv.GetStock(e.Request().Context(), sku)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return e.JSON(http.StatusOK, newStockResponse(sku, level, ok))
+}
+
+func (h *inventoryHandler) SetStock(e echo.Context) error {
+	var req setStockRequest
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(http.StatusBadRequest, errorResponse{Message: "invalid request body"})
+	}
+
+	ctx := e.Request().Context()
+	sku := e.Param("sku")
+	if err := h.catalogSrv.SetStock(ctx, sku, req.OnHand); err != nil {
+		return errorJSON(e, err)
+	}
+
+	level, ok, err := h.catalogSrv.GetStock(ctx, sku)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+	return e.JSON(http.StatusOK, newStockResponse(sku, level, ok))
+}
+
+func newStockResponse(sku string, level inventory.Level, tracked bool) stockResponse {
+	return stockResponse{
+		SKU:       sku,
+		Tracked:   tracked,
+		OnHand:    level.OnHand,
+		Reserved:  level.Reserved,
+		Available: level.Available(),
+	}
+}
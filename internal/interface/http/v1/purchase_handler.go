@@ -0,0 +1,77 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkittipat/try-cart/internal/app/service"
+)
+
+type purchaseHandler struct {
+	cartSrv *service.CartService
+}
+
+type (
+	recordPurchaseRequest struct {
+		Purchases map[string]int64 `json:"purchases"` // product ID -> units
+	}
+
+	purchasesResponse struct {
+		UserID    string           `json:"user_id"`
+		Purchases map[string]int64 `json:"purchases"`
+	}
+)
+
+// RegisterPurchaseHandler adds the purchase history routes, keyed by user ID.
+// Orders completed elsewhere are recorded here so MaxPerCustomer counts them.
+func RegisterPurchaseHandler(
+	router *echo.Group,
+	cartSrv *service.CartService,
+) {
+	handler := purchaseHandler{
+		cartSrv: cartSrv,
+	}
+
+	router.GET("/:userID/purchases", handler.GetPurchases)
+	router.POST("/:userID/purchases", handler.RecordPurchase)
+}
+
+func (h *purchaseHandler) GetPurchases(e echo.Context) error {
+	userID := e.Param("userID")
+	purchases, err := h.cartSrv.GetPurchases(e.Request().Context(), userID)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return e.JSON(http.StatusOK, newPurchasesResponse(userID, purchases))
+}
+
+// RecordPurchase adds the units, product ID -> units, to what the user bought
+func (h *purchaseHandler) RecordPurchase(e echo.Context) error {
+	var req recordPurchaseRequest
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(http.StatusBadRequest, errorResponse{Message: "invalid request body"})
+	}
+
+	ctx := e.Request().Context()
+	userID := e.Param("userID")
+	if err := h.cartSrv.RecordPurchase(ctx, userID, req.Purchases); err != nil {
+		return errorJSON(e, err)
+	}
+
+	purchases, err := h.cartSrv.GetPurchases(ctx, userID)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+	return e.JSON(http.StatusOK, newPurchasesResponse(userID, purchases))
+}
+
+func newPurchasesResponse(userID string, purchases map[string]int64) purchasesResponse {
+	if purchases == nil {
+		purchases = map[string]int64{}
+	}
+	return purchasesResponse{
+		UserID:    userID,
+		Purchases: purchases,
+	}
+}
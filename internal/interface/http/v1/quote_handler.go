@@ -0,0 +1,102 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkittipat/try-cart/internal/app/service"
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+)
+
+type quoteHandler struct {
+	quoteSrv *service.QuoteService
+}
+
+type quoteLineResponse struct {
+	ProductID string             `json:"product_id"`
+	SKU       string             `json:"sku"`
+	Quantity  int64              `json:"quantity"`
+	Measure   string             `json:"measure,omitempty"`
+	Unit      cart.UnitOfMeasure `json:"unit,omitempty"`
+	LineTotal string             `json:"line_total"`
+}
+
+type quoteBundleResponse struct {
+	PromotionID string `json:"promotion_id"`
+	Count       int64  `json:"count"`
+	Total       string `json:"total"`
+}
+
+type quoteResponse struct {
+	ID             string                `json:"id"`
+	Currency       string                `json:"currency"`
+	Rate           string                `json:"rate"`
+	RateAsOf       *time.Time            `json:"rate_as_of,omitempty"`
+	Lines          []quoteLineResponse   `json:"lines"`
+	Bundles        []quoteBundleResponse `json:"bundles"`
+	Subtotal       string                `json:"subtotal"`
+	Tax            string                `json:"tax"`
+	Total          string                `json:"total"`
+	ChargeCurrency string                `json:"charge_currency"`
+	ChargeTotal    string                `json:"charge_total"`
+}
+
+// RegisterQuoteHandler adds the display-currency quote route to the carts group
+func RegisterQuoteHandler(
+	router *echo.Group,
+	quoteSrv *service.QuoteService,
+) {
+	handler := quoteHandler{
+		quoteSrv: quoteSrv,
+	}
+
+	router.GET("/:cartID/quote", handler.GetQuote)
+}
+
+// GetQuote shows the cart in the currency given by the currency query parameter.
+// The charge fields are what checkout takes, in the cart's own currency.
+func (h *quoteHandler) GetQuote(e echo.Context) error {
+	cartID := e.Param("cartID")
+
+	quote, err := h.quoteSrv.Quote(e.Request().Context(), cartID, e.QueryParam("currency"))
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	resp := quoteResponse{
+		ID:             cartID,
+		Currency:       quote.Currency,
+		Rate:           quote.Rate.Rate.String(),
+		Lines:          make([]quoteLineResponse, 0, len(quote.Lines)),
+		Bundles:        make([]quoteBundleResponse, 0, len(quote.Bundles)),
+		Subtotal:       cart.DisplayAmount(quote.Subtotal, quote.Currency),
+		Tax:            cart.DisplayAmount(quote.Tax, quote.Currency),
+		Total:          cart.DisplayAmount(quote.Total, quote.Currency),
+		ChargeCurrency: quote.Charge.Currency,
+		ChargeTotal:    cart.DisplayAmount(quote.Charge.Amount, quote.Charge.Currency),
+	}
+	if !quote.Rate.AsOf.IsZero() {
+		asOf := quote.Rate.AsOf
+		resp.RateAsOf = &asOf
+	}
+	for _, line := range quote.Lines {
+		resp.Lines = append(resp.Lines, quoteLineResponse{
+			ProductID: line.ProductID,
+			SKU:       line.SKU,
+			Quantity:  line.Quantity,
+			Measure:   displayOptionalMeasure(line.Measure),
+			Unit:      line.Unit,
+			LineTotal: cart.DisplayAmount(line.LineTotal, quote.Currency),
+		})
+	}
+	for _, bundle := range quote.Bundles {
+		resp.Bundles = append(resp.Bundles, quoteBundleResponse{
+			PromotionID: bundle.PromotionID,
+			Count:       bundle.Count,
+			Total:       cart.DisplayAmount(bundle.Total, quote.Currency),
+		})
+	}
+
+	return e.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkittipat/try-cart/internal/app/service"
+	"github.com/pkittipat/try-cart/internal/domain/cart"
+	"github.com/pkittipat/try-cart/internal/domain/shipping"
+)
+
+type shippingHandler struct {
+	shippingSrv *service.ShippingService
+}
+
+type shippingResponse struct {
+	ID             string `json:"id"`
+	Zone           string `json:"zone"`
+	Items          int64  `json:"items"`
+	Weight         string `json:"weight"`
+	Currency       string `json:"currency,omitempty"`
+	Cost           string `json:"cost"`
+	Discount       string `json:"discount"`
+	Total          string `json:"total"`
+	FreeShippingID string `json:"free_shipping_id,omitempty"`
+}
+
+// RegisterShippingHandler adds the shipping routes to the carts group
+func RegisterShippingHandler(
+	router *echo.Group,
+	shippingSrv *service.ShippingService,
+) {
+	handler := shippingHandler{
+		shippingSrv: shippingSrv,
+	}
+
+	router.GET("/:cartID/shipping", handler.GetQuote)
+}
+
+// GetQuote prices shipping to the destination given by the
+// country, region and postal_code query parameters
+func (h *shippingHandler) GetQuote(e echo.Context) error {
+	cartID := e.Param("cartID")
+	dest := shipping.Destination{
+		Country:    e.QueryParam("country"),
+		Region:     e.QueryParam("region"),
+		PostalCode: e.QueryParam("postal_code"),
+	}
+
+	quote, err := h.shippingSrv.Quote(e.Request().Context(), cartID, dest)
+	if err != nil {
+		return errorJSON(e, err)
+	}
+
+	return e.JSON(http.StatusOK, shippingResponse{
+		ID:             cartID,
+		Zone:           quote.Zone,
+		Items:          quote.Parcel.Items,
+		Weight:         quote.Parcel.Weight.String(),
+		Currency:       quote.Total.Currency,
+		Cost:           cart.DisplayAmount(quote.Cost.Amount, quote.Cost.Currency),
+		Discount:       cart.DisplayAmount(quote.Discount.Amount, quote.Discount.Currency),
+		Total:          cart.DisplayAmount(quote.Total.Amount, quote.Total.Currency),
+		FreeShippingID: quote.FreeShippingID,
+	})
+}